@@ -0,0 +1,301 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+)
+
+// ScopeSummary is a scope name with the number of items it holds.
+type ScopeSummary struct {
+	Name     string `json:"name"`
+	IPv4     int    `json:"ipv4"`
+	IPv6     int    `json:"ipv6"`
+	Domains  int    `json:"domains"`
+	Excludes int    `json:"excludes"`
+}
+
+// ScopeDetail lists every item of a scope.
+type ScopeDetail struct {
+	Name     string            `json:"name"`
+	IPv4     []string          `json:"ipv4"`
+	IPv6     []string          `json:"ipv6"`
+	Domains  []string          `json:"domains"`
+	Excludes []string          `json:"excludes"`
+	Notes    map[string]string `json:"notes"`
+}
+
+// ItemsRequest is the body of requests operating on a batch of items.
+type ItemsRequest struct {
+	Items []string `json:"items"`
+}
+
+// CheckResult is the verdict for a single item.
+type CheckResult struct {
+	Item    string `json:"item"`
+	InScope bool   `json:"in_scope"`
+}
+
+// CIDRs is the address space of a scope.
+type CIDRs struct {
+	Included  []netip.Prefix `json:"included"`
+	Excluded  []netip.Prefix `json:"excluded"`
+	Effective []netip.Prefix `json:"effective"`
+}
+
+// Server exposes a Scoper over HTTP. Queries are served concurrently while
+// changes are made one at a time, against the scope files on disk, which are
+// reloaded when they change.
+type Server struct {
+	ScopeDir string
+	// Token is required as a bearer token on every request when set.
+	Token string
+	// ReadOnly rejects requests that would modify scope.
+	ReadOnly bool
+
+	mutex   sync.RWMutex
+	scoper  *scopious.Scoper
+	watcher *scopious.ScopeWatcher
+	mux     *http.ServeMux
+}
+
+// NewServer creates a server for the scopes stored in scopeDir.
+func NewServer(scopeDir string) (*Server, error) {
+	scoper, err := scopious.Open(scopeDir, scopious.WithCreate())
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := scoper.Watch()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ScopeDir: scopeDir,
+		scoper:   scoper,
+		watcher:  watcher,
+		mux:      http.NewServeMux(),
+	}
+	watcher.Subscribe(func(change scopious.ScopeChange) {
+		if change.Err != nil {
+			// keep serving the last scope until the files are fixed
+			log.Println("not reloading invalid scope:", change.Err)
+			return
+		}
+		s.reload()
+	})
+
+	s.mux.HandleFunc("GET /api/scopes", s.handleListScopes)
+	s.mux.HandleFunc("GET /api/scopes/{scope}", s.withScope(s.handleGetScope))
+	s.mux.HandleFunc("GET /api/scopes/{scope}/cidrs", s.withScope(s.handleCIDRs))
+	s.mux.HandleFunc("POST /api/scopes/{scope}/check", s.withItems(s.handleCheck))
+	s.mux.HandleFunc("POST /api/scopes/{scope}/prune", s.withItems(s.handlePrune))
+	s.mux.HandleFunc("POST /api/scopes/{scope}/explain", s.withItems(s.handleExplain))
+	s.mux.HandleFunc("POST /api/scopes/{scope}/items", s.modify(func(scope *scopious.Scope, items []string) {
+		scope.Add(items...)
+	}))
+	s.mux.HandleFunc("DELETE /api/scopes/{scope}/items", s.modify(func(scope *scopious.Scope, items []string) {
+		scope.Remove(items...)
+	}))
+	s.mux.HandleFunc("POST /api/scopes/{scope}/excludes", s.modify(func(scope *scopious.Scope, items []string) {
+		scope.AddExclude(items...)
+	}))
+	s.mux.HandleFunc("DELETE /api/scopes/{scope}/excludes", s.modify(func(scope *scopious.Scope, items []string) {
+		scope.RemoveExclude(items...)
+	}))
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Token != "" {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops watching the scope files.
+func (s *Server) Close() error {
+	return s.watcher.Close()
+}
+
+// reload reopens every scope once the watcher has seen the files on disk
+// change.
+func (s *Server) reload() {
+	scoper, err := scopious.Open(s.ScopeDir, scopious.WithCreate())
+	if err != nil {
+		// keep serving the last scope until the files are fixed
+		log.Println("not reloading scope:", err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.scoper = scoper
+	log.Println("reloaded scope")
+}
+
+// withScope looks up the scope named in the path, responding with a 404 when
+// it doesn't exist. The scope is only read while handler runs.
+func (s *Server) withScope(handler func(w http.ResponseWriter, r *http.Request, scope *scopious.Scope)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+		scope, ok := s.scoper.Lookup(r.PathValue("scope"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "scope not found")
+			return
+		}
+		handler(w, r, scope)
+	}
+}
+
+// withItems reads the items in the request body before looking up the scope,
+// so a slow client doesn't hold up other requests.
+func (s *Server) withItems(handler func(w http.ResponseWriter, scope *scopious.Scope, items []string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, ok := readItems(w, r)
+		if !ok {
+			return
+		}
+		s.withScope(func(w http.ResponseWriter, r *http.Request, scope *scopious.Scope) {
+			handler(w, scope, request.Items)
+		})(w, r)
+	}
+}
+
+// modify applies action to the items in the request body and saves the scope,
+// creating it when it doesn't exist.
+func (s *Server) modify(action func(scope *scopious.Scope, items []string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.ReadOnly {
+			writeError(w, http.StatusForbidden, "server is read only")
+			return
+		}
+
+		name := r.PathValue("scope")
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			writeError(w, http.StatusBadRequest, "invalid scope name")
+			return
+		}
+
+		request, ok := readItems(w, r)
+		if !ok {
+			return
+		}
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		scope := s.scoper.GetScope(name)
+		action(scope, request.Items)
+		err := scope.Save()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "error saving scope: "+err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, scopeDetail(name, scope))
+	}
+}
+
+func (s *Server) handleListScopes(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	summaries := []ScopeSummary{}
+	for _, name := range s.scoper.Names() {
+		scope, _ := s.scoper.Lookup(name)
+		summaries = append(summaries, ScopeSummary{
+			Name:     name,
+			IPv4:     len(scope.IPv4()),
+			IPv6:     len(scope.IPv6()),
+			Domains:  len(scope.AllDomains()),
+			Excludes: len(scope.Excludes()),
+		})
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) handleGetScope(w http.ResponseWriter, r *http.Request, scope *scopious.Scope) {
+	writeJSON(w, http.StatusOK, scopeDetail(r.PathValue("scope"), scope))
+}
+
+func (s *Server) handleCIDRs(w http.ResponseWriter, r *http.Request, scope *scopious.Scope) {
+	writeJSON(w, http.StatusOK, CIDRs{
+		Included:  scope.IncludedPrefixes(),
+		Excluded:  scope.ExcludedPrefixes(),
+		Effective: scope.EffectivePrefixes(),
+	})
+}
+
+func (s *Server) handleCheck(w http.ResponseWriter, scope *scopious.Scope, items []string) {
+	matcher := scope.Compile()
+	results := []CheckResult{}
+	for _, item := range items {
+		results = append(results, CheckResult{Item: item, InScope: matcher.Contains(item)})
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) handlePrune(w http.ResponseWriter, scope *scopious.Scope, items []string) {
+	matcher := scope.Compile()
+	pruned := ItemsRequest{Items: []string{}}
+	seen := map[string]bool{}
+	for _, item := range items {
+		if !seen[item] && matcher.Contains(item) {
+			seen[item] = true
+			pruned.Items = append(pruned.Items, item)
+		}
+	}
+	writeJSON(w, http.StatusOK, pruned)
+}
+
+func (s *Server) handleExplain(w http.ResponseWriter, scope *scopious.Scope, items []string) {
+	matcher := scope.Compile()
+	explanations := []scopious.Explanation{}
+	for _, item := range items {
+		explanations = append(explanations, matcher.Explain(item))
+	}
+	writeJSON(w, http.StatusOK, explanations)
+}
+
+func scopeDetail(name string, scope *scopious.Scope) ScopeDetail {
+	return ScopeDetail{
+		Name:     name,
+		IPv4:     scope.IPv4(),
+		IPv6:     scope.IPv6(),
+		Domains:  scope.AllDomains(),
+		Excludes: scope.Excludes(),
+		Notes:    scope.Notes(),
+	}
+}
+
+// readItems decodes an ItemsRequest body, responding with a 400 when it can't.
+func readItems(w http.ResponseWriter, r *http.Request) (ItemsRequest, bool) {
+	request := ItemsRequest{}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 32<<20)).Decode(&request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return request, false
+	}
+	return request, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+)
+
+func request(t *testing.T, server http.Handler, method string, path string, body any, response any) int {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+
+	if response != nil {
+		err := json.Unmarshal(recorder.Body.Bytes(), response)
+		if err != nil {
+			t.Fatalf("%s %s response %q: %s", method, path, recorder.Body, err)
+		}
+	}
+	return recorder.Code
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	server, err := NewServer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.Token = "secret"
+
+	unauthorized := httptest.NewRecorder()
+	server.ServeHTTP(unauthorized, httptest.NewRequest(http.MethodGet, "/api/scopes", nil))
+	if unauthorized.Code != http.StatusUnauthorized {
+		t.Errorf("without token status = %d, want 401", unauthorized.Code)
+	}
+
+	detail := ScopeDetail{}
+	status := request(t, server, http.MethodPost, "/api/scopes/client/items", ItemsRequest{Items: []string{"example.com", "203.0.113.0/24"}}, &detail)
+	if status != http.StatusOK || !reflect.DeepEqual(detail.Domains, []string{"example.com"}) {
+		t.Errorf("add items = %d %+v", status, detail)
+	}
+	request(t, server, http.MethodPost, "/api/scopes/client/excludes", ItemsRequest{Items: []string{"admin.example.com", "203.0.113.128/25"}}, nil)
+
+	checks := []CheckResult{}
+	request(t, server, http.MethodPost, "/api/scopes/client/check", ItemsRequest{Items: []string{"www.example.com", "admin.example.com", "203.0.113.7"}}, &checks)
+	want := []CheckResult{{"www.example.com", true}, {"admin.example.com", false}, {"203.0.113.7", true}}
+	if !reflect.DeepEqual(checks, want) {
+		t.Errorf("check = %v, want %v", checks, want)
+	}
+
+	pruned := ItemsRequest{}
+	request(t, server, http.MethodPost, "/api/scopes/client/prune", ItemsRequest{Items: []string{"203.0.113.200", "www.example.com", "www.example.com"}}, &pruned)
+	if !reflect.DeepEqual(pruned.Items, []string{"www.example.com"}) {
+		t.Errorf("prune = %v", pruned.Items)
+	}
+
+	explanations := []scopious.Explanation{}
+	request(t, server, http.MethodPost, "/api/scopes/client/explain", ItemsRequest{Items: []string{"api.admin.example.com"}}, &explanations)
+	if len(explanations) != 1 || explanations[0].Rule != "admin.example.com" {
+		t.Errorf("explain = %+v", explanations)
+	}
+
+	cidrs := map[string][]string{}
+	request(t, server, http.MethodGet, "/api/scopes/client/cidrs", nil, &cidrs)
+	if !reflect.DeepEqual(cidrs["effective"], []string{"203.0.113.0/25"}) {
+		t.Errorf("cidrs = %v", cidrs)
+	}
+
+	request(t, server, http.MethodDelete, "/api/scopes/client/excludes", ItemsRequest{Items: []string{"admin.example.com"}}, &detail)
+	if !reflect.DeepEqual(detail.Excludes, []string{"203.0.113.128/25"}) {
+		t.Errorf("remove exclude = %v", detail.Excludes)
+	}
+
+	if status := request(t, server, http.MethodGet, "/api/scopes/missing", nil, nil); status != http.StatusNotFound {
+		t.Errorf("missing scope status = %d, want 404", status)
+	}
+
+	// changes made outside the server are picked up
+	scope := scopious.NewScopeFromPath(filepath.Join(dir, "client"))
+	scope.Load()
+	scope.Add("example.net")
+	scope.Save()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		request(t, server, http.MethodGet, "/api/scopes/client", nil, &detail)
+		if reflect.DeepEqual(detail.Domains, []string{"example.com", "example.net"}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("after reload Domains = %v", detail.Domains)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.ReadOnly = true
+	if status := request(t, server, http.MethodPost, "/api/scopes/client/items", ItemsRequest{Items: []string{"example.org"}}, nil); status != http.StatusForbidden {
+		t.Errorf("read only add status = %d, want 403", status)
+	}
+}
+
+func TestServer_Concurrent(t *testing.T) {
+	server, err := NewServer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.Token = "secret"
+	request(t, server, http.MethodPost, "/api/scopes/client/items", ItemsRequest{Items: []string{"example.com"}}, nil)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			request(t, server, http.MethodPost, "/api/scopes/client/check", ItemsRequest{Items: []string{"www.example.com"}}, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			request(t, server, http.MethodPost, "/api/scopes/client/excludes", ItemsRequest{Items: []string{"admin.example.com"}}, nil)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestServer_SaveError(t *testing.T) {
+	dir := t.TempDir()
+	server, err := NewServer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.Token = "secret"
+
+	// a file where the scope directory should be can't be saved to
+	os.WriteFile(filepath.Join(dir, "broken"), nil, 0644)
+	if status := request(t, server, http.MethodPost, "/api/scopes/broken/items", ItemsRequest{Items: []string{"example.com"}}, nil); status != http.StatusInternalServerError {
+		t.Errorf("add status = %d, want 500", status)
+	}
+}
+
+func TestServer_StalledBody(t *testing.T) {
+	server, err := NewServer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	request(t, server, http.MethodPost, "/api/scopes/client/items", ItemsRequest{Items: []string{"example.com"}}, nil)
+
+	done := make(chan int)
+	go func() {
+		// clients that never finish sending their body
+		for _, path := range []string{"/api/scopes/client/check", "/api/scopes/client/excludes"} {
+			body, writer := io.Pipe()
+			defer writer.Close()
+			go server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, body))
+			// returns once the server is reading the body
+			writer.Write([]byte(`{"items": [`))
+		}
+		done <- request(t, server, http.MethodPost, "/api/scopes/client/check", ItemsRequest{Items: []string{"www.example.com"}}, nil)
+	}()
+	select {
+	case status := <-done:
+		if status != http.StatusOK {
+			t.Errorf("check status = %d, want 200", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("check was blocked by a stalled request body")
+	}
+}
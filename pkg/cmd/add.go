@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"bufio"
-	"log"
-	"os"
-
+	"github.com/analog-substance/scopious/pkg/scopious"
 	"github.com/spf13/cobra"
 )
 
@@ -17,32 +14,42 @@ var AddCmd = &cobra.Command{
 	cat customer-supplied.txt | scopious add
 
 	scopious add -i internal 10.0.0.0/22
-`,
+
+Add the prefixes an autonomous system announces, see --asn-db
+	scopious add AS64500
+
+Extract scope from free form text, review it, then add it
+	scopious add --extract scope-email.txt
+` + inputFormatExamples("add"),
 	Run: func(cmd *cobra.Command, args []string) {
 		scopeName, _ := cmd.Flags().GetString("scope")
-		all, _ := cmd.Flags().GetBool("all")
-		scope := scoperInstance.GetScope(scopeName)
-
-		if len(args) > 0 {
-			scope.Add(all, args...)
-		} else {
-			// no args, lets read from stdin
-			scanner := bufio.NewScanner(os.Stdin)
-			for scanner.Scan() {
-				scopeLine := scanner.Text()
-				scope.Add(all, scopeLine)
-			}
+		extract, _ := cmd.Flags().GetBool("extract")
+		if extract {
+			yes, _ := cmd.Flags().GetBool("yes")
+			items := extractFromInput(args)
+			printExtractedTable(items)
+			applyExtracted(scopeName, items, yes)
+			return
+		}
 
-			if scanner.Err() != nil {
-				log.Printf("STDIN scanner encountered an error: %s", scanner.Err())
+		scope := scoperInstance.GetScope(scopeName)
+		readInputRecords(cmd, args, func(record scopious.InputRecord) {
+			items, notes := expandASNItems(record.Items)
+			scope.Add(items...)
+			for item, note := range notes {
+				scope.SetNote(item, note)
 			}
-		}
+		})
 
-		scoperInstance.Save()
+		saveScopes()
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(AddCmd)
 	AddCmd.PersistentFlags().BoolP("all", "a", false, "show all addresses, even network and broadcast")
+	AddCmd.PersistentFlags().MarkDeprecated("all", "CIDRs are always added whole")
+	AddCmd.Flags().Bool("extract", false, "Extract scope from free form text in the given files or STDIN")
+	AddCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation when using --extract")
+	addInputFlags(AddCmd)
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ApproveCmd represents the approve command
+var ApproveCmd = &cobra.Command{
+	Use:   "approve [item...]",
+	Short: "Move pending items into scope",
+	Long: `Move items from the pending list into scope, noting where they were
+discovered. Items are read from STDIN when none are given. For example:
+
+	scopious approve www.example.com
+	scopious approve --relation in-scope
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		movePending(cmd, args, (*scopious.Scope).Approve)
+	},
+}
+
+// RejectCmd represents the reject command
+var RejectCmd = &cobra.Command{
+	Use:   "reject [item...]",
+	Short: "Move pending items to the exclude list",
+	Long: `Move items from the pending list to the exclude list, so they are not
+proposed again. Items are read from STDIN when none are given. For example:
+
+	scopious reject example.net
+	scopious reject --relation unrelated
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		movePending(cmd, args, func(scope *scopious.Scope, items ...string) ([]string, error) {
+			return scope.Reject(items...), nil
+		})
+	},
+}
+
+// movePending applies move to the pending items given as arguments, read from
+// STDIN or selected with --relation, printing the items moved.
+func movePending(cmd *cobra.Command, args []string, move func(scope *scopious.Scope, items ...string) ([]string, error)) {
+	scopeName, _ := cmd.Flags().GetString("scope")
+	relation, _ := cmd.Flags().GetString("relation")
+	scope := scoperInstance.GetScope(scopeName)
+
+	items := args
+	if relation != "" {
+		if len(args) > 0 {
+			log.Fatalln("give items or --relation, not both")
+		}
+		for _, review := range pendingReviews(cmd, scope) {
+			items = append(items, review.Item)
+		}
+	} else if len(items) == 0 {
+		// no args, lets read from stdin
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if strings.TrimSpace(scanner.Text()) != "" {
+				items = append(items, scanner.Text())
+			}
+		}
+
+		if scanner.Err() != nil {
+			log.Printf("STDIN scanner encountered an error: %s", scanner.Err())
+		}
+	}
+
+	moved, err := move(scope, items...)
+	for _, item := range moved {
+		fmt.Println(item)
+	}
+	saveScopes()
+	if len(moved) < len(items) {
+		fmt.Fprintf(os.Stderr, "%d items were not moved\n", len(items)-len(moved))
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(ApproveCmd)
+	addRelationFlag(ApproveCmd)
+	RootCmd.AddCommand(RejectCmd)
+	addRelationFlag(RejectCmd)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/viper"
+)
+
+// asnDatabase loads the IP to ASN dataset set with --asn-db or asn-db in the
+// config file.
+func asnDatabase() *scopious.ASNDatabase {
+	path := viper.GetString("asn-db")
+	if path == "" {
+		log.Fatalln("no ASN database configured, set --asn-db or asn-db in the config file")
+	}
+	db, err := scopious.LoadASNDatabase(path)
+	if err != nil {
+		log.Fatalln("error loading ASN database:", err)
+	}
+	return db
+}
+
+// expandASNItems replaces AS numbers with their prefixes, loading the ASN
+// database only when there is an AS number to expand.
+func expandASNItems(items []string) ([]string, map[string]string) {
+	hasASN := false
+	for _, item := range items {
+		if _, ok := scopious.ParseASN(item); ok {
+			hasASN = true
+			break
+		}
+	}
+	if !hasASN {
+		return items, nil
+	}
+
+	expanded, notes, err := asnDatabase().ExpandASNs(items)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return expanded, notes
+}
+
+// asnColumns formats the AS numbers and organisations of item for a table,
+// using "-" when the item isn't announced.
+func asnColumns(db *scopious.ASNDatabase, item string) string {
+	asns := []string{}
+	orgs := []string{}
+	for _, record := range db.LookupItem(item) {
+		asns = append(asns, fmt.Sprintf("AS%d", record.ASN))
+		orgs = append(orgs, record.Org)
+	}
+	if len(asns) == 0 {
+		return "-\t-"
+	}
+	return strings.Join(asns, ",") + "\t" + strings.Join(orgs, ", ")
+}
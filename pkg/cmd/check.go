@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// CheckCmd represents the check command
+var CheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check whether items are in scope",
+	Long: `Check whether items are in scope. Each item is printed with its verdict,
+the exit status is 1 when any item is out of scope. For example:
+
+	scopious check admin.example.com 10.0.0.1
+
+	cat hosts.txt | scopious check
+
+Show the AS number and organisation announcing each address, see --asn-db
+	scopious check --annotate 203.0.113.10
+` + inputFormatExamples("check"),
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		annotate, _ := cmd.Flags().GetBool("annotate")
+		scope := scoperInstance.GetScope(scopeName)
+
+		var asns *scopious.ASNDatabase
+		if annotate {
+			asns = asnDatabase()
+		}
+
+		matcher := scope.Compile()
+		allInScope := true
+		readInputRecords(cmd, args, func(record scopious.InputRecord) {
+			for _, item := range record.Items {
+				if item == "" {
+					continue
+				}
+
+				verdict := "in-scope"
+				if !matcher.Contains(item) {
+					verdict = "out-of-scope"
+					allInScope = false
+				}
+				if annotate {
+					fmt.Printf("%s\t%s\t%s\n", item, verdict, asnColumns(asns, item))
+					continue
+				}
+				fmt.Printf("%s\t%s\n", item, verdict)
+			}
+		})
+
+		if !allInScope {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(CheckCmd)
+	addInputFlags(CheckCmd)
+	CheckCmd.Flags().Bool("annotate", false, "Show the AS number and organisation of IP addresses and CIDRs")
+}
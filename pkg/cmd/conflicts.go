@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// ConflictsCmd represents the conflicts command
+var ConflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "Find address space and domains shared or disputed between scopes",
+	Long: `Compare every pair of scopes, reporting address space and domains included
+by both, and includes that another scope excludes. Overlapping CIDRs are found
+even when they aren't written the same way. The exit status is 1 when any
+conflict is found. For example:
+
+	scopious conflicts
+	scopious conflicts --json | jq 'select(.kind == "contradiction")'
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		conflicts := scoperInstance.Conflicts()
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, conflict := range conflicts {
+				encoder.Encode(conflict)
+			}
+		} else if len(conflicts) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KIND\tOVERLAP\tSCOPE\tITEM\tOTHER SCOPE\tOTHER ITEM")
+			for _, conflict := range conflicts {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", conflict.Kind, conflict.Overlap, conflict.Scope, conflict.Item, conflict.OtherScope, conflict.OtherItem)
+			}
+			w.Flush()
+		}
+
+		if len(conflicts) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ConflictsCmd)
+	ConflictsCmd.Flags().Bool("json", false, "Print conflicts as JSON lines")
+}
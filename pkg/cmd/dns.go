@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/analog-substance/scopious/pkg/proxy"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSCmd represents the dns command
+var DNSCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Run a DNS forwarder that refuses out of scope names",
+	Long: `Run a DNS forwarder that only forwards queries for in scope names to the
+upstream server. Other names are refused, reverse lookups are checked against
+the address being looked up. Scope files are reloaded when they change. For
+example:
+
+	scopious dns --listen 127.0.0.1:5353 --upstream 1.1.1.1:53
+
+Remove A and AAAA answers with excluded addresses
+	scopious dns --upstream 1.1.1.1:53 --filter-answers
+
+Keep a JSON lines record of every query
+	scopious dns --upstream 1.1.1.1:53 --query-log dns-queries.jsonl
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		listen, _ := cmd.Flags().GetString("listen")
+		upstream, _ := cmd.Flags().GetString("upstream")
+		filterAnswers, _ := cmd.Flags().GetBool("filter-answers")
+		nxdomain, _ := cmd.Flags().GetBool("nxdomain")
+		queryLogPath, _ := cmd.Flags().GetString("query-log")
+
+		forwarder := proxy.NewDNSForwarder(watchScope(scopeName), upstream)
+		forwarder.FilterAnswers = filterAnswers
+		if nxdomain {
+			forwarder.DenyRCode = dnsmessage.RCodeNameError
+		}
+		if queryLogPath != "" {
+			queryLog, err := os.OpenFile(queryLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				log.Fatalln("error opening query log:", err)
+			}
+			defer queryLog.Close()
+			forwarder.QueryLog = queryLog
+		}
+
+		log.Printf("DNS forwarder listening on %s, forwarding to %s", listen, upstream)
+		err := forwarder.ListenAndServe(listen)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(DNSCmd)
+	DNSCmd.Flags().StringP("listen", "l", "127.0.0.1:5353", "Address to listen on")
+	DNSCmd.Flags().StringP("upstream", "u", "127.0.0.1:53", "Upstream DNS server address")
+	DNSCmd.Flags().Bool("filter-answers", false, "Remove A and AAAA answers with excluded addresses")
+	DNSCmd.Flags().Bool("nxdomain", false, "Answer NXDOMAIN instead of REFUSED for out of scope names")
+	DNSCmd.Flags().String("query-log", "", "File to append a JSON line to for every query")
+}
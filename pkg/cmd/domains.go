@@ -29,7 +29,7 @@ Print in scope root domains:
 		var domains []string
 
 		if allRootDomains {
-			domains = scope.GetRootDomainSlice(false)
+			domains = scope.AllRootDomains()
 		} else if showRootDomains {
 			domains = scope.RootDomains()
 		} else {
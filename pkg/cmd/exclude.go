@@ -18,6 +18,9 @@ Sometimes not every subdomain underneath a domain or IP address
 in a CIDR is in scope.
 
 	scopious exclude admin.example.com
+
+Exclude the prefixes an autonomous system announces, see --asn-db
+	scopious exclude AS64511
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		shouldList, _ := cmd.Flags().GetBool("list")
@@ -25,26 +28,27 @@ in a CIDR is in scope.
 		scope := scoperInstance.GetScope(scopeName)
 
 		if shouldList {
-			for excluded := range scope.Excludes {
+			for _, excluded := range scope.Excludes() {
 				fmt.Println(excluded)
 			}
 			return
 		}
 		if len(args) > 0 {
-			scope.AddExclude(args...)
+			items, _ := expandASNItems(args)
+			scope.AddExclude(items...)
 		} else {
 			// no args, lets read from stdin
 			scanner := bufio.NewScanner(os.Stdin)
 			for scanner.Scan() {
-				scopeLine := scanner.Text()
-				scope.AddExclude(scopeLine)
+				items, _ := expandASNItems([]string{scanner.Text()})
+				scope.AddExclude(items...)
 			}
 
 			if scanner.Err() != nil {
 				log.Printf("STDIN scanner encountered an error: %s", scanner.Err())
 			}
 		}
-		scoperInstance.Save()
+		saveScopes()
 	},
 }
 
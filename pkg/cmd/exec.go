@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ExecCmd represents the exec command
+var ExecCmd = &cobra.Command{
+	Use:   "exec -- command [args...]",
+	Short: "Run a tool with its out of scope targets removed",
+	Long: `Run a tool after removing out of scope targets from its arguments and target
+list files. CIDRs and ranges that are only partly in scope are narrowed down to
+the in scope CIDRs. For example:
+
+	scopious exec -- nmap -sV 203.0.113.0/24 10.9.9.9
+	scopious exec -- nuclei -l urls.txt -t http/
+	scopious exec --dry-run -- masscan -p443 -iL ranges.txt
+
+nmap, masscan, nuclei, httpx and ffuf are understood, for any other tool every
+positional argument that looks like an IP address, CIDR, range, URL or domain
+is checked. Use --strict to refuse to run rather than rewrite the command.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		strict, _ := cmd.Flags().GetBool("strict")
+		tool, _ := cmd.Flags().GetString("tool")
+		generic, _ := cmd.Flags().GetBool("generic")
+
+		if tool == "" {
+			tool = filepath.Base(args[0])
+		}
+		spec := scopious.ToolSpecs[tool]
+		if generic {
+			spec = nil
+		}
+
+		scope := scoperInstance.GetScope(scopeName)
+		filtered, err := scope.FilterCommand(args, spec)
+		if err != nil {
+			log.Fatalln("refusing to run:", err)
+		}
+		defer filtered.Cleanup()
+
+		for _, change := range filtered.Changes {
+			message := fmt.Sprintf("removed %s from %s: %s", change.Target, change.Source, change.Reason)
+			if len(change.Replacement) > 0 {
+				message = fmt.Sprintf("narrowed %s from %s to %s", change.Target, change.Source, strings.Join(change.Replacement, " "))
+			}
+			log.Println(message)
+		}
+
+		if dryRun {
+			if filtered.StdinTargets {
+				log.Println("targets read from stdin will be filtered")
+			}
+			fmt.Println(strings.Join(filtered.Args, " "))
+			return
+		}
+		if strict && len(filtered.Changes) > 0 {
+			filtered.Cleanup()
+			log.Fatalln("refusing to run: out of scope targets given")
+		}
+		if len(filtered.Changes) > 0 && filtered.Targets == 0 {
+			filtered.Cleanup()
+			log.Fatalln("refusing to run: no in scope targets left")
+		}
+
+		command := exec.Command(filtered.Args[0], filtered.Args[1:]...)
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+		if filtered.StdinTargets {
+			stdin, err := command.StdinPipe()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			go filterStdin(scope, stdin)
+		} else {
+			command.Stdin = os.Stdin
+		}
+		err = command.Run()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			filtered.Cleanup()
+			os.Exit(exitErr.ExitCode())
+		}
+		if err != nil {
+			filtered.Cleanup()
+			log.Fatalln(err)
+		}
+	},
+}
+
+// filterStdin copies the in scope lines of stdin to a tool reading its targets
+// from there.
+func filterStdin(scope *scopious.Scope, stdin io.WriteCloser) {
+	defer stdin.Close()
+	matcher := scope.Compile()
+	err := scope.PruneStream(context.Background(), os.Stdin, stdin, scopious.PruneOptions{
+		Dedup: scopious.PruneDedupNone,
+		Explain: func(line string) scopious.Explanation {
+			explanation := matcher.Explain(line)
+			if !explanation.InScope && strings.TrimSpace(line) != "" {
+				log.Printf("removed %s from stdin: %s", line, explanation)
+			}
+			return explanation
+		},
+	})
+	if err != nil && !errors.Is(err, os.ErrClosed) {
+		log.Println("error filtering stdin:", err)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(ExecCmd)
+	// flags after the command belong to it
+	ExecCmd.Flags().SetInterspersed(false)
+	ExecCmd.Flags().Bool("dry-run", false, "Print the filtered command and what was removed without running it")
+	ExecCmd.Flags().Bool("strict", false, "Refuse to run when any target is out of scope")
+	ExecCmd.Flags().String("tool", "", "Treat the command as this tool, for renamed binaries")
+	ExecCmd.Flags().Bool("generic", false, "Check every positional argument that looks like a target")
+}
@@ -1,13 +1,12 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"log"
 	"net"
-	"os"
+	"net/netip"
 	"strings"
 
+	"github.com/analog-substance/scopious/pkg/scopious"
 	"github.com/analog-substance/scopious/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -20,39 +19,41 @@ var ExpandCmd = &cobra.Command{
 	cat customer-supplied.txt | scopious expand
 
 	scopious expand 10.0.0.0/22
-`,
+
+Only show addresses of some classes
+	scopious expand --class public,cgnat 100.64.0.0/9
+` + inputFormatExamples("expand"),
 	Run: func(cmd *cobra.Command, args []string) {
 
 		all, _ := cmd.Flags().GetBool("all")
 		public, _ := cmd.Flags().GetBool("public")
 		private, _ := cmd.Flags().GetBool("private")
 
-		if public && private {
-			// silly, that is the same as the default...
-			public = false
-			private = false
+		classes := classFlag(cmd)
+		if public {
+			classes[scopious.AddressClassPublic] = true
+		}
+		if private {
+			classes[scopious.AddressClassPrivate] = true
 		}
 
-		if len(args) > 0 {
-			for _, scopeLine := range args {
-				processScopeLine(scopeLine, all, public, private)
-			}
-		} else {
-			// no args, lets read from stdin
-			scanner := bufio.NewScanner(os.Stdin)
-			for scanner.Scan() {
-				scopeLine := scanner.Text()
-				processScopeLine(scopeLine, all, public, private)
-			}
-
-			if scanner.Err() != nil {
-				log.Printf("STDIN scanner encountered an error: %s", scanner.Err())
+		readInputRecords(cmd, args, func(record scopious.InputRecord) {
+			for _, scopeLine := range record.Items {
+				processScopeLine(scopeLine, all, classes)
 			}
-		}
+		})
 	},
 }
 
-func processScopeLine(scopeLine string, all, public, private bool) {
+func isAddrInClasses(ip *net.IP, classes map[string]bool) bool {
+	if len(classes) == 0 {
+		return true
+	}
+	addr, ok := netip.AddrFromSlice(*ip)
+	return ok && classes[scopious.ClassifyAddr(addr)]
+}
+
+func processScopeLine(scopeLine string, all bool, classes map[string]bool) {
 	if strings.Contains(scopeLine, "/") {
 		// perhaps we have a CIDR
 		ips, err := utils.GetAllIPs(scopeLine, all)
@@ -62,7 +63,7 @@ func processScopeLine(scopeLine string, all, public, private bool) {
 		}
 
 		for _, ip := range ips {
-			if (public && !ip.IsPrivate()) || (private && ip.IsPrivate()) || (!public && !private) {
+			if isAddrInClasses(ip, classes) {
 				fmt.Println(ip.String())
 			}
 		}
@@ -70,7 +71,7 @@ func processScopeLine(scopeLine string, all, public, private bool) {
 
 	ip := net.ParseIP(scopeLine)
 	if ip != nil {
-		if (public && !ip.IsPrivate()) || (private && ip.IsPrivate()) || (!public && !private) {
+		if isAddrInClasses(&ip, classes) {
 			fmt.Println(ip.String())
 		}
 	}
@@ -79,6 +80,8 @@ func processScopeLine(scopeLine string, all, public, private bool) {
 func init() {
 	RootCmd.AddCommand(ExpandCmd)
 	ExpandCmd.PersistentFlags().BoolP("all", "a", false, "show all addresses, even network and broadcast")
-	ExpandCmd.PersistentFlags().Bool("public", false, "Only return public IPs")
-	ExpandCmd.PersistentFlags().Bool("private", false, "Only return private IPs")
+	ExpandCmd.PersistentFlags().Bool("public", false, "Only return public IPs, same as --class public")
+	ExpandCmd.PersistentFlags().Bool("private", false, "Only return private IPs, same as --class private")
+	addClassFlag(ExpandCmd)
+	addInputFlags(ExpandCmd)
 }
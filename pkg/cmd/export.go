@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ExportCmd represents the export command
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export scope for other tools",
+	Long: `Export scope as configuration for other tools. For example:
+
+Burp Suite project options, load via Project > Project options > Load
+	scopious export --format burp > burp-scope.json
+
+ZAP context, import via File > Import Context
+	scopious export --format zap -o ./exports
+
+Scanner target and exclude lists
+	scopious export --format nmap -o ./exports
+	nmap -iL exports/default-nmap-targets.txt --excludefile exports/default-nmap-exclude.txt
+
+	scopious export --format masscan -o ./exports
+	masscan -iL exports/default-masscan-targets.txt --excludefile exports/default-masscan-exclude.txt
+
+	scopious export --format nuclei -o ./exports
+	nuclei -l exports/default-nuclei-targets.txt -eh exports/default-nuclei-exclude.txt
+
+Scanner targets minus excludes always equal the effective scope. Domains are
+only listed for tools that accept them, masscan gets addresses only.
+
+Compiled matcher for agents embedding scopious, see scopious.Matcher
+	scopious export --format matcher-binary -o ./exports
+
+Supported formats: burp, zap, nmap, masscan, nuclei, matcher, matcher-binary
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		format, _ := cmd.Flags().GetString("format")
+		outputDir, _ := cmd.Flags().GetString("output-dir")
+		scope := scoperInstance.GetScope(scopeName)
+
+		result, err := scope.Export(format)
+		if err != nil {
+			log.Fatalln("error exporting scope:", err)
+		}
+
+		for _, warning := range result.Warnings {
+			fmt.Fprintln(os.Stderr, "warning:", warning)
+		}
+
+		if outputDir != "" {
+			err = os.MkdirAll(outputDir, 0755)
+			if err != nil {
+				log.Fatalln("error creating output dir:", err)
+			}
+		}
+
+		for _, file := range result.Files {
+			if outputDir != "" {
+				path := filepath.Join(outputDir, file.Name)
+				err = os.WriteFile(path, file.Content, 0644)
+				if err != nil {
+					log.Fatalln("error writing export:", err)
+				}
+				fmt.Println(path)
+				continue
+			}
+
+			if len(result.Files) > 1 {
+				fmt.Printf("==> %s <==\n", file.Name)
+			}
+			os.Stdout.Write(file.Content)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ExportCmd)
+	ExportCmd.Flags().StringP("format", "f", scopious.ExportFormatBurp, "Export format: burp, zap, nmap, masscan, nuclei, matcher, matcher-binary")
+	ExportCmd.Flags().StringP("output-dir", "o", "", "Write exported files to this directory instead of STDOUT")
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ExtractCmd represents the extract command
+var ExtractCmd = &cobra.Command{
+	Use:   "extract [file...]",
+	Short: "Extract scope from free form text",
+	Long: `Extract IP addresses, CIDRs, ranges, URLs and domains from free form text
+like emails, exported documents and Markdown tables. Items under an "out of
+scope" or "exclusions" heading are treated as excludes. Names like readme.md or
+backup.zip are taken for files, not domains. For example:
+
+Review what would be extracted
+	scopious extract scope-email.txt
+
+Review and then add to scope
+	scopious extract --apply scope-email.txt
+	pbpaste | scopious add --extract
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		apply, _ := cmd.Flags().GetBool("apply")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		items := extractFromInput(args)
+		printExtractedTable(items)
+		if !apply {
+			return
+		}
+		applyExtracted(scopeName, items, yes)
+	},
+}
+
+// extractFromInput extracts scope items from the files in args, or STDIN when
+// there are none.
+func extractFromInput(args []string) []scopious.ExtractedItem {
+	readers := []io.Reader{}
+	for _, path := range args {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalln("error opening file:", err)
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, os.Stdin)
+	}
+
+	items := []scopious.ExtractedItem{}
+	for _, reader := range readers {
+		extracted, err := scopious.Extract(reader)
+		if err != nil {
+			log.Printf("error extracting scope: %s", err)
+		}
+		items = append(items, extracted...)
+	}
+	return items
+}
+
+func printExtractedTable(items []scopious.ExtractedItem) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tITEM\tKIND\tLINE\tSOURCE")
+	for _, item := range items {
+		action := "include"
+		if item.Exclude {
+			action = "exclude"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", action, item.Item, item.Kind, item.Line, item.Source)
+	}
+	w.Flush()
+}
+
+func applyExtracted(scopeName string, items []scopious.ExtractedItem, yes bool) {
+	if len(items) == 0 {
+		fmt.Fprintln(os.Stderr, "nothing to add")
+		return
+	}
+
+	if !yes && !confirm(fmt.Sprintf("Apply %d items to the %s scope?", len(items), scopeName)) {
+		fmt.Fprintln(os.Stderr, "aborted")
+		return
+	}
+
+	scope := scoperInstance.GetScope(scopeName)
+	scope.Import(scopious.ExtractedImport(items))
+	saveScopes()
+}
+
+// confirm asks a yes/no question on the terminal. STDIN usually holds the data
+// being processed, so the answer is read from /dev/tty.
+func confirm(question string) bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to prompt for confirmation, use --yes:", err)
+		return false
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	RootCmd.AddCommand(ExtractCmd)
+	ExtractCmd.Flags().Bool("apply", false, "Add extracted items to scope after review")
+	ExtractCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation before applying")
+}
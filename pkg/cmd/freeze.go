@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// FreezeCmd represents the freeze command
+var FreezeCmd = &cobra.Command{
+	Use:   "freeze",
+	Short: "Snapshot scope as approved by the client",
+	Long: `Snapshot the scope, its description and testing windows, as approved by the
+client. The snapshot is kept in the scope's snapshots directory and its SHA-256
+digest printed. The digest covers a canonical form of the scope and the time
+it was frozen. For example:
+
+	scopious freeze
+	scopious freeze --canonical
+
+Sign the snapshot with an Ed25519 key, the client can then be sent a copy
+along with the public key
+
+	openssl genpkey -algorithm ed25519 -out scope-key.pem
+	openssl pkey -in scope-key.pem -pubout -out scope-key.pub
+	scopious freeze --key scope-key.pem -o external-scope.json
+
+Use scopious verify to check the live scope still matches the snapshot.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		keyPath, _ := cmd.Flags().GetString("key")
+		output, _ := cmd.Flags().GetString("output")
+		canonical, _ := cmd.Flags().GetBool("canonical")
+
+		scope, ok := scoperInstance.Lookup(scopeName)
+		if !ok {
+			log.Fatalln("scope not found:", scopeName)
+		}
+		snapshot := scope.Freeze()
+		if canonical {
+			fmt.Print(string(snapshot.Canonical()))
+			return
+		}
+
+		if keyPath != "" {
+			key, err := scopious.LoadSigningKey(keyPath)
+			if err != nil {
+				log.Fatalln("error loading signing key:", err)
+			}
+			snapshot.Sign(key)
+		}
+
+		path, err := scope.SaveSnapshot(snapshot)
+		if err != nil {
+			log.Fatalln("error saving snapshot:", err)
+		}
+		if output != "" {
+			err = snapshot.Save(output)
+			if err != nil {
+				log.Fatalln("error saving snapshot:", err)
+			}
+		}
+		fmt.Println(snapshot.Digest, path)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(FreezeCmd)
+	FreezeCmd.Flags().String("key", "", "Ed25519 private key PEM file to sign the snapshot with")
+	FreezeCmd.Flags().StringP("output", "o", "", "Also write the snapshot to this file")
+	FreezeCmd.Flags().Bool("canonical", false, "Print the canonical form that is hashed and signed instead")
+}
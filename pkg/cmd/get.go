@@ -18,6 +18,9 @@ var GetCmd = &cobra.Command{
 		ipv6, _ := cmd.Flags().GetBool("ipv6")
 		domain, _ := cmd.Flags().GetBool("domain")
 		exclude, _ := cmd.Flags().GetBool("exclude")
+		notes, _ := cmd.Flags().GetBool("notes")
+		description, _ := cmd.Flags().GetBool("description")
+		windows, _ := cmd.Flags().GetBool("windows")
 
 		if ipv4 {
 			fmt.Println(scoperInstance.GetScopeIPv4Path(scopeName))
@@ -35,6 +38,18 @@ var GetCmd = &cobra.Command{
 			fmt.Println(scoperInstance.GetScopeExcludePath(scopeName))
 		}
 
+		if notes {
+			fmt.Println(scoperInstance.GetScopeNotesPath(scopeName))
+		}
+
+		if description {
+			fmt.Println(scoperInstance.GetScopeDescriptionPath(scopeName))
+		}
+
+		if windows {
+			fmt.Println(scoperInstance.GetScopeWindowsPath(scopeName))
+		}
+
 	},
 }
 
@@ -44,4 +59,7 @@ func init() {
 	GetCmd.Flags().BoolP("ipv6", "6", false, "Get IPv6 file path")
 	GetCmd.Flags().BoolP("domain", "d", false, "Get domains file path")
 	GetCmd.Flags().BoolP("exclude", "x", false, "Get exclude file path")
+	GetCmd.Flags().BoolP("notes", "n", false, "Get notes file path")
+	GetCmd.Flags().Bool("description", false, "Get description file path")
+	GetCmd.Flags().Bool("windows", false, "Get testing windows file path")
 }
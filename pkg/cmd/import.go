@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ImportCmd represents the import command
+var ImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import a bug bounty program or spreadsheet scope",
+	Long: `Import a bug bounty program scope export or a spreadsheet exported to
+CSV. In scope assets are added to scope, out of scope assets are excluded.
+For example:
+
+	scopious import --format hackerone structured_scopes.json
+
+	curl -s https://example.com/bugcrowd.json | scopious import -f bugcrowd
+
+Supported formats: hackerone, bugcrowd, intigriti, csv
+
+Assets that cannot be expressed as scope (mobile apps, source code
+repositories, hardware) are reported on STDERR.
+
+CSV columns are mapped with --map. Rows are routed to the scope named in the
+scope column, notes are kept alongside each item in notes.tsv. Defaults are
+asset=Asset,type=Type,include=In Scope,scope=Scope,note=Notes
+
+	scopious import -f csv --map asset=Host,include=InScope,scope=Environment scope.csv
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		format, _ := cmd.Flags().GetString("format")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		var input io.Reader = os.Stdin
+		if len(args) > 0 {
+			file, err := os.Open(args[0])
+			if err != nil {
+				log.Fatalln("error opening import file:", err)
+			}
+			defer file.Close()
+			input = file
+		}
+
+		if format == scopious.ImportFormatCSV {
+			mapping, _ := cmd.Flags().GetString("map")
+			importTabular(scopeName, mapping, dryRun, input)
+			return
+		}
+
+		assets, err := scopious.ParseProgramScope(format, input)
+		if err != nil {
+			log.Fatalln("error parsing import file:", err)
+		}
+		result := scopious.MapProgramAssets(assets)
+
+		for _, asset := range result.Unmapped {
+			fmt.Fprintf(os.Stderr, "unmapped %s asset: %s\n", asset.Type, asset.Identifier)
+		}
+		for _, warning := range result.Warnings {
+			fmt.Fprintln(os.Stderr, "warning:", warning)
+		}
+
+		if dryRun {
+			for _, include := range result.Includes {
+				fmt.Println("include", include)
+			}
+			for _, exclude := range result.Excludes {
+				fmt.Println("exclude", exclude)
+			}
+			return
+		}
+
+		scope := scoperInstance.GetScope(scopeName)
+		scope.Import(result)
+		saveScopes()
+
+		fmt.Fprintf(os.Stderr, "imported %d includes and %d excludes, %d assets unmapped\n", len(result.Includes), len(result.Excludes), len(result.Unmapped))
+	},
+}
+
+func importTabular(defaultScope string, mapping string, dryRun bool, input io.Reader) {
+	columns, err := scopious.ParseColumnMapping(mapping)
+	if err != nil {
+		log.Fatalln("error parsing column mapping:", err)
+	}
+
+	result, err := scopious.ParseTabularScope(input, columns, defaultScope)
+	if err != nil {
+		log.Fatalln("error parsing import file:", err)
+	}
+
+	for _, invalid := range result.Invalid {
+		fmt.Fprintln(os.Stderr, invalid.Error())
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+
+	if dryRun {
+		for _, row := range result.Rows {
+			action := "include"
+			if !row.Include {
+				action = "exclude"
+			}
+			for _, item := range row.Items {
+				fmt.Println(action, row.Scope, item)
+			}
+		}
+		return
+	}
+
+	byScope := result.ByScope()
+	for _, scopeName := range result.ScopeNames() {
+		imported := byScope[scopeName]
+		scoperInstance.GetScope(scopeName).Import(imported)
+		fmt.Fprintf(os.Stderr, "imported %d includes and %d excludes into %s\n", len(imported.Includes), len(imported.Excludes), scopeName)
+	}
+	saveScopes()
+
+	fmt.Fprintf(os.Stderr, "%d rows invalid\n", len(result.Invalid))
+}
+
+func init() {
+	RootCmd.AddCommand(ImportCmd)
+	ImportCmd.Flags().StringP("format", "f", scopious.ImportFormatHackerOne, "Import format: hackerone, bugcrowd, intigriti, csv")
+	ImportCmd.Flags().String("map", "", "CSV column mapping, asset=Host,type=Type,include=InScope,scope=Environment,note=Notes")
+	ImportCmd.Flags().BoolP("dry-run", "n", false, "Print mapped scope items without saving them")
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// inputFormatExamples documents --input-format for the given command.
+func inputFormatExamples(command string) string {
+	return strings.ReplaceAll(`
+Tool output can be read directly by setting --input-format:
+
+	nmap -oX - 10.0.0.0/24 | scopious COMMAND --input-format nmap-xml
+	masscan -oJ - 10.0.0.0/24 | scopious COMMAND --input-format masscan-json
+	httpx -json < hosts.txt | scopious COMMAND --input-format jsonl --field url
+	scopious COMMAND --input-format nessus < scan.nessus
+`, "COMMAND", command)
+}
+
+// addInputFlags adds the flags used to select an input format.
+func addInputFlags(cmd *cobra.Command) {
+	cmd.Flags().String("input-format", scopious.InputFormatLine, "Input format: line, nmap-xml, masscan-json, jsonl, nessus")
+	cmd.Flags().String("field", scopious.DefaultInputField, "Field holding the host when using --input-format jsonl, e.g. url or a")
+}
+
+func inputFlags(cmd *cobra.Command) (format string, field string) {
+	format, _ = cmd.Flags().GetString("input-format")
+	field, _ = cmd.Flags().GetString("field")
+	return
+}
+
+// readInputRecords calls action for every argument, or for every record read
+// from STDIN in the selected input format when there are no arguments.
+func readInputRecords(cmd *cobra.Command, args []string, action func(record scopious.InputRecord)) {
+	if len(args) > 0 {
+		for _, arg := range args {
+			action(scopious.InputRecord{Raw: arg, Items: []string{arg}})
+		}
+		return
+	}
+
+	format, field := inputFlags(cmd)
+	err := scopious.ReadInput(os.Stdin, format, field, action)
+	if err != nil {
+		log.Printf("STDIN reader encountered an error: %s", err)
+	}
+}
@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"sort"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/analog-substance/scopious/pkg/scopious"
 	"github.com/spf13/cobra"
 )
 
@@ -18,24 +22,58 @@ Show in scope ips
 
 Expand CIDRs and remove excluded ips
 	scopious ips -x
+
+Only show public addresses, and show each address's classes and provider
+	scopious ips --class public --classify
+
+Show the AS number and organisation announcing each item, see --asn-db
+	scopious ips --annotate
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		scopeName, _ := cmd.Flags().GetString("scope")
 		shouldExpand, _ := cmd.Flags().GetBool("expand")
 		all, _ := cmd.Flags().GetBool("all")
+		classify, _ := cmd.Flags().GetBool("classify")
+		annotate, _ := cmd.Flags().GetBool("annotate")
+		classes := classFlag(cmd)
 		scope := scoperInstance.GetScope(scopeName)
 
 		var scopeStrings []string
 		if shouldExpand {
-			scopeStrings = scope.AllExpanded(all)
+			scopeStrings = scope.Expand(scopious.ExpandOptions{NetworkAndBroadcast: all})
 			sort.Strings(scopeStrings)
 		} else {
 			scopeStrings = scope.AllIPs()
 		}
 
+		var providers *scopious.ProviderRanges
+		if classify {
+			providers = providerRanges()
+		}
+		var asns *scopious.ASNDatabase
+		if annotate {
+			asns = asnDatabase()
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		for _, ip := range scopeStrings {
-			fmt.Println(ip)
+			ipClasses := scopious.ClassifyItem(ip)
+			if !scopious.MatchesAddressClasses(ipClasses, classes) {
+				continue
+			}
+			columns := []string{ip}
+			if classify {
+				provider := strings.Join(providers.LookupItem(ip), ",")
+				if provider == "" {
+					provider = "-"
+				}
+				columns = append(columns, strings.Join(ipClasses, ","), provider)
+			}
+			if annotate {
+				columns = append(columns, asnColumns(asns, ip))
+			}
+			fmt.Fprintln(w, strings.Join(columns, "\t"))
 		}
+		w.Flush()
 	},
 }
 
@@ -43,4 +81,7 @@ func init() {
 	RootCmd.AddCommand(IpsCmd)
 	IpsCmd.Flags().BoolP("expand", "x", false, "Expand CIDRS and remove excluded things")
 	IpsCmd.PersistentFlags().BoolP("all", "a", false, "show all addreses, even network and broadcast")
+	IpsCmd.Flags().Bool("classify", false, "Show the address classes and cloud or CDN provider of each item")
+	IpsCmd.Flags().Bool("annotate", false, "Show the AS number and organisation of each item")
+	addClassFlag(IpsCmd)
 }
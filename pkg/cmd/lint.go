@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// LintCmd represents the lint command
+var LintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check scope files for invalid, redundant and contradictory items",
+	Long: `Check scope files for invalid, redundant and contradictory items. Findings
+are printed with a severity, the exit status is 1 when any error is found. For
+example:
+
+	scopious lint
+	scopious lint --json | jq 'select(.severity == "error")'
+
+Remove redundant and fully excluded items, and rewrite CIDRs with host bits set
+	scopious lint --fix
+
+Scopes with "external" in their name, or linted with --external, are checked for
+private, CGNAT, loopback, link-local, documentation, multicast and reserved
+address space as well. Addresses belonging to cloud and CDN providers are
+noted, see scopious providers.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		fix, _ := cmd.Flags().GetBool("fix")
+		external, _ := cmd.Flags().GetBool("external")
+		external = external || strings.Contains(strings.ToLower(scopeName), "external")
+
+		scope := scoperInstance.GetScope(scopeName)
+		options := scopious.LintOptions{External: external, Providers: providerRanges()}
+		findings := scope.Lint(options)
+
+		if fix {
+			fixed := scope.Fix(findings)
+			saveScopes()
+			fmt.Fprintf(os.Stderr, "fixed %d findings\n", fixed)
+			findings = scope.Lint(options)
+		}
+
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, finding := range findings {
+				encoder.Encode(finding)
+			}
+		} else if len(findings) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SEVERITY\tCHECK\tFILE\tITEM\tMESSAGE")
+			for _, finding := range findings {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", finding.Severity, finding.Check, finding.File, finding.Item, finding.Message)
+			}
+			w.Flush()
+		}
+
+		for _, finding := range findings {
+			if finding.Severity == scopious.LintSeverityError {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(LintCmd)
+	LintCmd.Flags().Bool("json", false, "Print findings as JSON lines")
+	LintCmd.Flags().Bool("fix", false, "Remove redundant and fully excluded items")
+	LintCmd.Flags().Bool("external", false, "Report address space that is not publicly routable")
+}
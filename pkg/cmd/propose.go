@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ProposeCmd represents the propose command
+var ProposeCmd = &cobra.Command{
+	Use:   "propose [item...]",
+	Short: "Add discovered items to the pending list for review",
+	Long: `Add discovered items to the scope's pending list without changing what is in
+scope. Items already in scope, excluded or pending are skipped, the items
+added are printed. For example:
+
+	subfinder -d example.com -silent | scopious propose --source subfinder
+	scopious propose --source "whois reverse lookup" example.net
+
+Pending items are reviewed with scopious review, then moved into scope with
+scopious approve or excluded with scopious reject.
+` + inputFormatExamples("propose"),
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		source, _ := cmd.Flags().GetString("source")
+		scope := scoperInstance.GetScope(scopeName)
+
+		proposed := 0
+		readInputRecords(cmd, args, func(record scopious.InputRecord) {
+			for _, item := range scope.Propose(source, record.Items...) {
+				fmt.Println(item)
+				proposed++
+			}
+		})
+
+		saveScopes()
+		fmt.Fprintf(os.Stderr, "proposed %d items\n", proposed)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ProposeCmd)
+	ProposeCmd.Flags().String("source", "", "Where the items were discovered, like the tool that found them")
+	addInputFlags(ProposeCmd)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/analog-substance/scopious/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ProvidersCmd represents the providers command
+var ProvidersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Show or update the cloud and CDN provider ranges",
+	Long: `Show the cloud and CDN provider ranges used to flag in scope addresses
+hosted by a provider. Cloudflare and Fastly ranges are bundled, --update
+downloads the current AWS, GCP, Cloudflare, Fastly and DigitalOcean ranges into
+providers.tsv in the scope directory, which is used from then on. For example:
+
+	scopious providers
+	scopious providers --update
+
+The file can be edited by hand, each line holds a CIDR and a provider name
+separated by a tab.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		update, _ := cmd.Flags().GetBool("update")
+		path := providerRangesPath()
+
+		if update {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			ranges, err := scopious.UpdateProviderRanges(ctx, http.DefaultClient, scopious.ProviderSources)
+			if err != nil {
+				log.Fatalln("error updating provider ranges:", err)
+			}
+
+			file, err := os.Create(path)
+			if err != nil {
+				log.Fatalln("error saving provider ranges:", err)
+			}
+			defer file.Close()
+			err = ranges.Write(file)
+			if err != nil {
+				log.Fatalln("error saving provider ranges:", err)
+			}
+			log.Printf("saved provider ranges to %s", path)
+		}
+
+		source := path
+		if _, err := os.Stat(path); err != nil {
+			source = "bundled"
+		}
+		ranges := providerRanges()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "PROVIDER\tCIDRS\tADDRESSES\tSOURCE\n")
+		for _, provider := range ranges.Providers() {
+			prefixes := ranges.Prefixes(provider)
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", provider, len(prefixes), utils.CountAddresses(prefixes), source)
+		}
+		w.Flush()
+	},
+}
+
+func providerRangesPath() string {
+	return filepath.Join(viper.GetString("scope-dir"), scopious.ProviderRangesFile)
+}
+
+// providerRanges loads the updated provider ranges, or the bundled ones when
+// they haven't been updated.
+func providerRanges() *scopious.ProviderRanges {
+	ranges, err := scopious.LoadProviderRanges(providerRangesPath())
+	if err != nil {
+		log.Fatalln("error loading provider ranges:", err)
+	}
+	return ranges
+}
+
+func addClassFlag(cmd *cobra.Command) {
+	cmd.Flags().String("class", "", "Only show addresses of these classes, comma separated: "+strings.Join(scopious.AddressClasses, ", "))
+}
+
+func classFlag(cmd *cobra.Command) map[string]bool {
+	classList, _ := cmd.Flags().GetString("class")
+	classes, err := scopious.ParseAddressClasses(classList)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return classes
+}
+
+func init() {
+	RootCmd.AddCommand(ProvidersCmd)
+	ProvidersCmd.Flags().Bool("update", false, "Download the current provider ranges")
+}
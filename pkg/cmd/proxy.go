@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/proxy"
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ProxyCmd represents the proxy command
+var ProxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Run an HTTP proxy that blocks out of scope requests",
+	Long: `Run an HTTP forward proxy that only forwards requests to in scope hosts.
+HTTPS is tunnelled with CONNECT after the target host has been checked.
+Out of scope requests get a 403 and are logged. Scope is host based, request
+paths aren't checked. Scope files are reloaded when they change. For example:
+
+	scopious proxy --listen 127.0.0.1:8081
+	curl -x http://127.0.0.1:8081 https://www.example.com/
+
+Chain to an upstream proxy like Burp
+	scopious proxy --upstream http://127.0.0.1:8080
+
+Only allow web ports
+	scopious proxy --ports 80,443
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listen, _ := cmd.Flags().GetString("listen")
+		upstream, _ := cmd.Flags().GetString("upstream")
+
+		var upstreamURL *url.URL
+		if upstream != "" {
+			var err error
+			upstreamURL, err = url.Parse(upstream)
+			if err != nil || upstreamURL.Host == "" {
+				log.Fatalln("invalid upstream proxy URL:", upstream)
+			}
+		}
+
+		guard := scopeGuard(cmd)
+		log.Printf("proxy listening on %s", listen)
+		err := http.ListenAndServe(listen, proxy.NewHTTPProxy(guard, upstreamURL))
+		if err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+// addGuardFlags adds the flags used to configure a scope guard.
+func addGuardFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ports", "", "Comma separated ports to allow, any port when empty")
+}
+
+// ensureScope creates the named scope on disk if it doesn't exist yet, so it
+// can be watched for changes.
+func ensureScope(scopeName string) {
+	if _, exists := scoperInstance.Lookup(scopeName); exists {
+		return
+	}
+	err := scoperInstance.GetScope(scopeName).Save()
+	if err != nil {
+		log.Fatalln("error creating scope:", err)
+	}
+}
+
+// watchScope follows the named scope as its files are edited, logging every
+// reload.
+func watchScope(scopeName string) *scopious.WatchedScope {
+	ensureScope(scopeName)
+	watcher, err := scoperInstance.Watch()
+	if err != nil {
+		log.Fatalln("error watching scope:", err)
+	}
+	watcher.Subscribe(func(change scopious.ScopeChange) {
+		if change.Scope != scopeName {
+			return
+		}
+		if change.Err != nil {
+			log.Printf("%s scope invalid, keeping previous scope: %s", scopeName, change.Err)
+			return
+		}
+		log.Printf("reloaded %s scope", scopeName)
+	})
+	return watcher.Watched(scopeName)
+}
+
+// scopeGuard creates a guard for the selected scope that reloads as its files
+// change.
+func scopeGuard(cmd *cobra.Command) *proxy.Guard {
+	scopeName, _ := cmd.Flags().GetString("scope")
+	ports, _ := cmd.Flags().GetString("ports")
+
+	guard := proxy.NewGuard(watchScope(scopeName))
+	for _, port := range strings.Split(ports, ",") {
+		port = strings.TrimSpace(port)
+		if port == "" {
+			continue
+		}
+		number, err := strconv.Atoi(port)
+		if err != nil {
+			log.Fatalln("invalid port:", port)
+		}
+		guard.Ports[number] = true
+	}
+	return guard
+}
+
+func init() {
+	RootCmd.AddCommand(ProxyCmd)
+	ProxyCmd.Flags().StringP("listen", "l", "127.0.0.1:8081", "Address to listen on")
+	ProxyCmd.Flags().StringP("upstream", "u", "", "Upstream proxy URL to forward requests through, e.g. http://127.0.0.1:8080")
+	addGuardFlags(ProxyCmd)
+}
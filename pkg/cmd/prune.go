@@ -1,11 +1,16 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/analog-substance/scopious/pkg/scopious"
 	"github.com/spf13/cobra"
 )
 
@@ -16,29 +21,148 @@ var PruneCmd = &cobra.Command{
 	Long: `Prune excluded scope items from input
 
 cat urls.txt | scopious prune
+` + inputFormatExamples("prune") + `
+Structured input is written back out as the original records, so the pruned
+output can still be used by tools expecting that format. A record is kept when
+any of its hosts or addresses is in scope and none of them are excluded.
+
+Domains that resolve to addresses outside IP scope can be pruned as well
+
+	cat hosts.txt | scopious prune --require-resolved-in-scope --cache resolved.json
+
+Only keep addresses of some classes, domains are kept as they have no address
+
+	cat hosts.txt | scopious prune --class public
+
+Long running pipelines can pick up scope edits as they happen
+
+	tail -f hosts.txt | scopious prune --watch
+
+Every line kept is remembered so it is only printed once. Use a bloom filter
+to bound memory on very large inputs, the odd unique line may then be dropped
+
+	cat urls.txt | scopious prune --dedup bloom --dedup-memory 256
+
+Review what was pruned and why, with a count of lines for each reason written
+to stderr
+
+	cat hosts.txt | scopious prune --invert --reasons
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		scopeName, _ := cmd.Flags().GetString("scope")
 		scope := scoperInstance.GetScope(scopeName)
 
-		scopePrinted := map[string]bool{}
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			scopeLine := scanner.Text()
-			if scope.IsInScope(scopeLine) {
-				if _, ok := scopePrinted[scopeLine]; !ok {
-					scopePrinted[scopeLine] = true
-					fmt.Println(scopeLine)
+		matcher := scope.Compile()
+		currentMatcher := func() *scopious.Matcher {
+			return matcher
+		}
+		watch, _ := cmd.Flags().GetBool("watch")
+		if watch {
+			watched := watchScope(scopeName)
+			currentMatcher = watched.Matcher
+		}
+
+		classes := classFlag(cmd)
+		explain := func(item string) scopious.Explanation {
+			return currentMatcher().Explain(item)
+		}
+
+		requireResolved, _ := cmd.Flags().GetBool("require-resolved-in-scope")
+		if requireResolved {
+			resolver := domainResolver(cmd)
+			defer saveResolutionCache(resolver)
+			explain = func(item string) scopious.Explanation {
+				return currentMatcher().ExplainResolved(context.Background(), resolver, item)
+			}
+		}
+
+		if len(classes) > 0 {
+			explainScope := explain
+			explain = func(item string) scopious.Explanation {
+				explanation := explainScope(item)
+				itemClasses := scopious.ClassifyItem(item)
+				if explanation.InScope && itemClasses != nil && !scopious.MatchesAddressClasses(itemClasses, classes) {
+					explanation.InScope = false
+					explanation.Reason = "address class not selected"
+					explanation.Rule = strings.Join(itemClasses, ",")
 				}
+				return explanation
+			}
+		}
+
+		invert, _ := cmd.Flags().GetBool("invert")
+		reasons, _ := cmd.Flags().GetBool("reasons")
+		inputFormat, field := inputFlags(cmd)
+		if inputFormat != scopious.InputFormatLine {
+			if reasons {
+				log.Fatalln("--reasons only works with line input")
 			}
+			err := scopious.FilterInput(os.Stdin, os.Stdout, inputFormat, field, func(record scopious.InputRecord) bool {
+				return record.InScope(explain) != invert
+			})
+			if err != nil {
+				log.Printf("STDIN reader encountered an error: %s", err)
+			}
+			return
 		}
 
-		if scanner.Err() != nil {
-			log.Printf("STDIN scanner encountered an error: %s", scanner.Err())
+		var summary map[string]int
+		if invert || reasons {
+			summary = map[string]int{}
+		}
+		dedup, _ := cmd.Flags().GetString("dedup")
+		dedupMemory, _ := cmd.Flags().GetInt("dedup-memory")
+		threads, _ := cmd.Flags().GetInt("threads")
+		err := scope.PruneStream(context.Background(), os.Stdin, os.Stdout, scopious.PruneOptions{
+			Dedup:     dedup,
+			BloomSize: dedupMemory << 20,
+			Workers:   threads,
+			Explain:   explain,
+			Invert:    invert,
+			Reasons:   reasons,
+			Summary:   summary,
+		})
+		if err != nil {
+			log.Fatalln("error pruning input:", err)
+		}
+		if summary != nil {
+			printPruneSummary(summary)
 		}
 	},
 }
 
+// printPruneSummary writes how many lines there were for each reason to
+// stderr, so it doesn't end up in the pruned output.
+func printPruneSummary(summary map[string]int) {
+	reasons := []string{}
+	for reason := range summary {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if summary[reasons[i]] != summary[reasons[j]] {
+			return summary[reasons[i]] > summary[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LINES\tREASON")
+	for _, reason := range reasons {
+		fmt.Fprintf(w, "%d\t%s\n", summary[reason], reason)
+	}
+	w.Flush()
+}
+
 func init() {
 	RootCmd.AddCommand(PruneCmd)
+	addInputFlags(PruneCmd)
+	PruneCmd.Flags().Bool("watch", false, "Reload scope as its files are edited")
+	addClassFlag(PruneCmd)
+	PruneCmd.Flags().Bool("require-resolved-in-scope", false, "Prune domains that resolve to addresses outside IP scope")
+	addResolverFlags(PruneCmd)
+	PruneCmd.Flags().String("dedup", scopious.PruneDedupExact, "Drop repeated lines: none, exact or bloom")
+	PruneCmd.Flags().Int("dedup-memory", scopious.DefaultBloomSize>>20, "MiB of memory to drop repeated lines with when using --dedup bloom")
+	PruneCmd.Flags().Bool("invert", false, "Print the lines that are out of scope instead")
+	PruneCmd.Flags().Bool("reasons", false, "Follow each line with a tab and why it is in or out of scope")
+	PruneCmd.Flags().IntP("threads", "t", runtime.NumCPU(), "Number of lines to check at once")
 }
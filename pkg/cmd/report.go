@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// ReportCmd represents the report command
+var ReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render the scope as a document for reports",
+	Long: `Render the scope as a Markdown or HTML document for a report's scope
+appendix. The description, testing windows, domains grouped by root domain,
+CIDRs with address counts, excludes and notes are included. Output is sorted so
+report versions can be diffed. For example:
+
+	scopious report > scope.md
+	scopious report --format html > scope.html
+
+The description and testing windows are read from description.txt and
+windows.txt in the scope directory, see scopious get --description --windows.
+
+Use your own Go template, rendered with text/template for Markdown and
+html/template for HTML
+	scopious report --template appendix.md.tmpl
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		format, _ := cmd.Flags().GetString("format")
+		templatePath, _ := cmd.Flags().GetString("template")
+
+		templateText := ""
+		if templatePath != "" {
+			data, err := os.ReadFile(templatePath)
+			if err != nil {
+				log.Fatalln("error reading template:", err)
+			}
+			templateText = string(data)
+		}
+
+		scope := scoperInstance.GetScope(scopeName)
+		err := scope.Report(os.Stdout, format, templateText)
+		if err != nil {
+			log.Fatalln("error rendering report:", err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ReportCmd)
+	ReportCmd.Flags().StringP("format", "f", "markdown", "Report format, markdown or html")
+	ReportCmd.Flags().StringP("template", "t", "", "Template file to render instead of the built in one")
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// ResolveCmd represents the resolve command
+var ResolveCmd = &cobra.Command{
+	Use:   "resolve [domain...]",
+	Short: "Check whether domains resolve to in scope addresses",
+	Long: `Resolve domains and check whether the addresses they resolve to are in
+scope. A domain may be in scope while resolving to a CDN or SaaS provider that
+is not. Every in scope domain is resolved when none are given. For example:
+
+	scopious resolve
+	scopious resolve --resolver 127.0.0.1:5353 admin.example.com
+
+Resolutions can be cached, later runs can then use --offline
+
+	scopious resolve --cache resolved.json
+	scopious resolve --cache resolved.json --offline
+
+Each domain is reported as in-scope, excluded (resolves to an excluded
+address), out-of-scope (resolves outside IP scope) or unresolved. The exit
+status is 1 when any domain is not in-scope.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		threads, _ := cmd.Flags().GetInt("threads")
+		scope := scoperInstance.GetScope(scopeName)
+
+		domains := args
+		if len(domains) == 0 {
+			domains = scope.AllDomains()
+		}
+
+		resolver := domainResolver(cmd)
+		resolutions := make([]scopious.Resolution, len(domains))
+
+		work := make(chan int)
+		wg := sync.WaitGroup{}
+		for i := 0; i < max(threads, 1); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for index := range work {
+					resolutions[index] = resolver.Resolve(context.Background(), domains[index])
+				}
+			}()
+		}
+		for index := range domains {
+			work <- index
+		}
+		close(work)
+		wg.Wait()
+		saveResolutionCache(resolver)
+
+		allInScope := true
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STATUS\tDOMAIN\tADDRESSES")
+		for _, resolution := range resolutions {
+			status := scope.ClassifyResolution(resolution)
+			if status != scopious.ResolutionInScope {
+				allInScope = false
+			}
+
+			addrs := strings.Join(resolution.Addrs, ",")
+			if addrs == "" {
+				addrs = resolution.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", status, resolution.Domain, addrs)
+		}
+		w.Flush()
+
+		if !allInScope {
+			os.Exit(1)
+		}
+	},
+}
+
+// addResolverFlags adds the flags used to configure DNS resolution.
+func addResolverFlags(cmd *cobra.Command) {
+	cmd.Flags().String("resolver", "", "DNS server address to resolve with, e.g. 127.0.0.1:53 (default system resolver)")
+	cmd.Flags().String("cache", "", "File to cache resolutions in")
+	cmd.Flags().Bool("offline", false, "Only use cached resolutions")
+	cmd.Flags().Duration("timeout", 5*time.Second, "Timeout for each lookup")
+}
+
+func domainResolver(cmd *cobra.Command) *scopious.DomainResolver {
+	address, _ := cmd.Flags().GetString("resolver")
+	cachePath, _ := cmd.Flags().GetString("cache")
+	offline, _ := cmd.Flags().GetBool("offline")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	resolver := scopious.NewDomainResolver(address)
+	resolver.Offline = offline
+	resolver.Timeout = timeout
+	if cachePath != "" {
+		cache, err := scopious.LoadResolutionCache(cachePath)
+		if err != nil {
+			log.Fatalln("error loading resolution cache:", err)
+		}
+		resolver.Cache = cache
+	} else if offline {
+		log.Fatalln("--offline requires --cache")
+	}
+	return resolver
+}
+
+func saveResolutionCache(resolver *scopious.DomainResolver) {
+	if resolver.Cache == nil || resolver.Offline {
+		return
+	}
+	err := resolver.Cache.Save()
+	if err != nil {
+		log.Println("error saving resolution cache:", err)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(ResolveCmd)
+	ResolveCmd.Flags().IntP("threads", "t", 10, "Number of concurrent lookups")
+	addResolverFlags(ResolveCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ReviewCmd represents the review command
+var ReviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "List pending items by how they relate to scope",
+	Long: `List the items waiting on the pending list, see scopious propose. Each is
+classed by how it relates to the existing scope:
+
+	in-scope   already covered, like a subdomain of an in scope domain
+	excluded   matches an exclude
+	adjacent   may belong to the client, like example.net when example.com is
+	           in scope, or an address announced by the same AS as in scope
+	           address space when --asn-db is set
+	unrelated  none of the above
+
+For example:
+
+	scopious review
+	scopious review --relation adjacent --json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		scope := scoperInstance.GetScope(scopeName)
+		reviews := pendingReviews(cmd, scope)
+
+		if asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, review := range reviews {
+				encoder.Encode(review)
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RELATION\tITEM\tSOURCE\tPROPOSED\tREASON")
+		for _, review := range reviews {
+			reason := scopious.Explanation{Reason: review.Reason, Rule: review.Rule}
+			source := review.Source
+			if source == "" {
+				source = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", review.Relation, review.Item, source, review.Proposed.Format("2006-01-02"), reason)
+		}
+		w.Flush()
+	},
+}
+
+// addRelationFlag adds --relation to select pending items by how they relate
+// to scope.
+func addRelationFlag(cmd *cobra.Command) {
+	cmd.Flags().String("relation", "", "Only pending items with this relation: in-scope, excluded, adjacent or unrelated")
+}
+
+// pendingReviews reviews the pending items of scope, keeping those with the
+// relation selected by --relation. AS numbers are only checked when an ASN
+// database is configured.
+func pendingReviews(cmd *cobra.Command, scope *scopious.Scope) []scopious.CandidateReview {
+	relation, _ := cmd.Flags().GetString("relation")
+	var db *scopious.ASNDatabase
+	if viper.GetString("asn-db") != "" {
+		db = asnDatabase()
+	}
+
+	reviews := []scopious.CandidateReview{}
+	for _, review := range scope.Review(db) {
+		if relation == "" || review.Relation == relation {
+			reviews = append(reviews, review)
+		}
+	}
+	return reviews
+}
+
+func init() {
+	RootCmd.AddCommand(ReviewCmd)
+	ReviewCmd.Flags().Bool("json", false, "Print pending items as JSON lines")
+	addRelationFlag(ReviewCmd)
+}
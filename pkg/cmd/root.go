@@ -7,6 +7,7 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"log"
 	"os"
 )
 
@@ -64,7 +65,11 @@ list excluded things
 			state.Debug = true
 		}
 		scopeDir := viper.GetString("scope-dir")
-		scoperInstance = scopious.FromPath(scopeDir)
+		var err error
+		scoperInstance, err = scopious.Open(scopeDir, scopious.WithCreate())
+		if err != nil {
+			log.Fatalln("error loading scope:", err)
+		}
 	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -103,6 +108,7 @@ func init() {
 
 	RootCmd.PersistentFlags().String("scope-dir", scopious.DefaultScopeDir, "where scope files are located.")
 	RootCmd.PersistentFlags().StringP("scope", "s", scopious.DefaultScope, "Scope name")
+	RootCmd.PersistentFlags().String("asn-db", "", "IP to ASN dataset, an iptoasn.com TSV or GeoLite2 ASN CSV file")
 
 	//rootCmd.PersistentFlags().String("domains-file", "scope-domains.txt", "where in-scope domains are located.")
 	//rootCmd.PersistentFlags().String("ips-file", "scope-ips.txt", "where in-scope IP addresses are located.")
@@ -110,6 +116,7 @@ func init() {
 	//rootCmd.PersistentFlags().String("ignore-ips", "ignore-scope-ips.txt", "where out-of-scope domains addresses are located.")
 
 	viper.BindPFlag("scope-dir", RootCmd.PersistentFlags().Lookup("scope-dir"))
+	viper.BindPFlag("asn-db", RootCmd.PersistentFlags().Lookup("asn-db"))
 	//viper.BindPFlag("ips-file", rootCmd.PersistentFlags().Lookup("ips-file"))
 	//viper.BindPFlag("ignore-domains", rootCmd.PersistentFlags().Lookup("ignore-domains"))
 	//viper.BindPFlag("ignore-ips", rootCmd.PersistentFlags().Lookup("ignore-ips"))
@@ -118,6 +125,24 @@ func init() {
 	// when this action is called directly.
 }
 
+// saveScopes writes every scope to disk, exiting when they can't be saved.
+// Scopes that no longer match the snapshot they were last frozen into are
+// warned about.
+func saveScopes() {
+	err := scoperInstance.Save()
+	if err != nil {
+		log.Fatalln("error saving scope:", err)
+	}
+
+	for _, name := range scoperInstance.Names() {
+		scope, _ := scoperInstance.Lookup(name)
+		snapshot, err := scope.LatestSnapshot(nil)
+		if err == nil && snapshot != nil && len(snapshot.Deviations(scope)) > 0 {
+			fmt.Fprintf(os.Stderr, "warning: scope %s no longer matches the snapshot frozen %s, see scopious verify\n", name, snapshot.Frozen.Format("2006-01-02"))
+		}
+	}
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
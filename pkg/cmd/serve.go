@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/analog-substance/scopious/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ServeCmd represents the serve command
+var ServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a JSON API for scope queries and management",
+	Long: `Serve a JSON API for scope queries and management. Scope files are
+reloaded when they change. For example:
+
+	scopious serve --listen 127.0.0.1:8765 --token-env SCOPIOUS_TOKEN
+
+	curl -H "Authorization: Bearer $SCOPIOUS_TOKEN" localhost:8765/api/scopes
+	curl -d '{"items": ["www.example.com"]}' localhost:8765/api/scopes/default/check
+
+Endpoints:
+
+	GET    /api/scopes                   list scopes
+	GET    /api/scopes/{scope}           list items, excludes and notes
+	GET    /api/scopes/{scope}/cidrs     included, excluded and effective CIDRs
+	POST   /api/scopes/{scope}/check     check items, {"items": [...]}
+	POST   /api/scopes/{scope}/prune     return the in scope items
+	POST   /api/scopes/{scope}/explain   explain why items are or aren't in scope
+	POST   /api/scopes/{scope}/items     add items
+	DELETE /api/scopes/{scope}/items     remove items
+	POST   /api/scopes/{scope}/excludes  add excludes
+	DELETE /api/scopes/{scope}/excludes  remove excludes
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listen, _ := cmd.Flags().GetString("listen")
+		tokenEnv, _ := cmd.Flags().GetString("token-env")
+		readOnly, _ := cmd.Flags().GetBool("read-only")
+
+		server, err := api.NewServer(viper.GetString("scope-dir"))
+		if err != nil {
+			log.Fatalln("error loading scope:", err)
+		}
+		server.ReadOnly = readOnly
+		if tokenEnv != "" {
+			server.Token = os.Getenv(tokenEnv)
+			if server.Token == "" {
+				log.Fatalf("%s is not set", tokenEnv)
+			}
+		}
+
+		log.Printf("serving scope API on %s", listen)
+		err = http.ListenAndServe(listen, server)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ServeCmd)
+	ServeCmd.Flags().StringP("listen", "l", "127.0.0.1:8765", "Address to listen on")
+	ServeCmd.Flags().String("token-env", "", "Environment variable holding the bearer token required on every request")
+	ServeCmd.Flags().Bool("read-only", false, "Reject requests that modify scope")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/analog-substance/scopious/pkg/proxy"
+	"github.com/spf13/cobra"
+)
+
+// SocksCmd represents the socks command
+var SocksCmd = &cobra.Command{
+	Use:   "socks",
+	Short: "Run a SOCKS5 proxy that blocks out of scope connections",
+	Long: `Run a SOCKS5 proxy that only connects to in scope hosts. CONNECT and UDP
+ASSOCIATE are supported. Domain names are resolved and the addresses checked
+too, connections to excluded addresses are denied and logged. Scope files are
+reloaded when they change. For example:
+
+	scopious socks --listen 127.0.0.1:1080
+	proxychains -q nmap -sT -Pn -p 443 www.example.com
+
+Also deny domains resolving outside IP scope
+	scopious socks --require-resolved-in-scope
+
+Chain to an upstream SOCKS5 proxy, UDP ASSOCIATE is not available when chained
+	scopious socks --upstream 127.0.0.1:9050
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listen, _ := cmd.Flags().GetString("listen")
+		upstream, _ := cmd.Flags().GetString("upstream")
+		requireResolved, _ := cmd.Flags().GetBool("require-resolved-in-scope")
+
+		guard := scopeGuard(cmd)
+		guard.Resolver = domainResolver(cmd)
+		guard.RequireResolvedInScope = requireResolved
+
+		socksProxy, err := proxy.NewSOCKS5Proxy(guard, upstream)
+		if err != nil {
+			log.Fatalln("error creating SOCKS5 proxy:", err)
+		}
+
+		log.Printf("SOCKS5 proxy listening on %s", listen)
+		err = socksProxy.ListenAndServe(listen)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(SocksCmd)
+	SocksCmd.Flags().StringP("listen", "l", "127.0.0.1:1080", "Address to listen on")
+	SocksCmd.Flags().StringP("upstream", "u", "", "Upstream SOCKS5 proxy address to connect through, e.g. 127.0.0.1:9050")
+	SocksCmd.Flags().Bool("require-resolved-in-scope", false, "Deny domains that resolve to addresses outside IP scope")
+	addGuardFlags(SocksCmd)
+	addResolverFlags(SocksCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// StatsCmd represents the stats command
+var StatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Print scope size statistics",
+	Long: `Print scope size statistics for quoting and scheduling. Address counts are
+worked out from CIDR sizes after excludes are applied, nothing is expanded so
+large IPv6 ranges are fine. For example:
+
+	scopious stats
+	scopious stats --json | jq .addresses.total
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		stats := scoperInstance.GetScope(scopeName).Stats()
+		if asJSON {
+			data, err := json.MarshalIndent(stats, "", "  ")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			fmt.Println(string(data))
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "addresses in scope\t%s\n", stats.Addresses.Total)
+		fmt.Fprintf(w, "  ipv4\t%s\n", stats.Addresses.IPv4)
+		fmt.Fprintf(w, "  ipv6\t%s\n", stats.Addresses.IPv6)
+		fmt.Fprintf(w, "  public\t%s\n", stats.Addresses.Public)
+		fmt.Fprintf(w, "  private\t%s\n", stats.Addresses.Private)
+		fmt.Fprintf(w, "addresses included\t%s\n", stats.Addresses.Included)
+		fmt.Fprintf(w, "addresses excluded\t%s\n", stats.Addresses.Excluded)
+		fmt.Fprintf(w, "cidrs\t%d\n", stats.CIDRs)
+		fmt.Fprintf(w, "ips\t%d\n", stats.IPs)
+		fmt.Fprintf(w, "effective cidrs\t%d\n", stats.EffectiveCIDRs)
+		fmt.Fprintf(w, "domains\t%d\n", stats.Domains)
+		fmt.Fprintf(w, "wildcard domains\t%d\n", stats.WildcardDomains)
+		fmt.Fprintf(w, "root domains\t%d\n", len(stats.RootDomains))
+
+		rootDomains := []string{}
+		for rootDomain := range stats.RootDomains {
+			rootDomains = append(rootDomains, rootDomain)
+		}
+		sort.Slice(rootDomains, func(i, j int) bool {
+			countI, countJ := stats.RootDomains[rootDomains[i]], stats.RootDomains[rootDomains[j]]
+			if countI != countJ {
+				return countI > countJ
+			}
+			return rootDomains[i] < rootDomains[j]
+		})
+		for _, rootDomain := range rootDomains {
+			fmt.Fprintf(w, "  %s\t%d\n", rootDomain, stats.RootDomains[rootDomain])
+		}
+
+		fmt.Fprintf(w, "excluded cidrs\t%d\n", stats.ExcludedCIDRs)
+		fmt.Fprintf(w, "excluded ips\t%d\n", stats.ExcludedIPs)
+		fmt.Fprintf(w, "excluded domains\t%d\n", stats.ExcludedDomains)
+		w.Flush()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(StatsCmd)
+	StatsCmd.Flags().Bool("json", false, "Print statistics as JSON")
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// VerifyCmd represents the verify command
+var VerifyCmd = &cobra.Command{
+	Use:   "verify [snapshot.json]",
+	Short: "Check scope against a frozen snapshot",
+	Long: `Check a snapshot made with scopious freeze hasn't been altered, then list how
+the live scope deviates from it. The latest signed snapshot of the scope that
+verifies is used when none is given, or the latest unsigned one when none are
+signed. Otherwise the snapshot is compared with the scope it was taken of. The
+exit status is 1 when the snapshot can't be verified or the scope deviates
+from it. For example:
+
+	scopious verify
+	scopious verify --public-key scope-key.pub external-scope.json
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scopeName, _ := cmd.Flags().GetString("scope")
+		publicKeyPath, _ := cmd.Flags().GetString("public-key")
+
+		var publicKey ed25519.PublicKey
+		var err error
+		if publicKeyPath != "" {
+			publicKey, err = scopious.LoadPublicKey(publicKeyPath)
+			if err != nil {
+				log.Fatalln("error loading public key:", err)
+			}
+		}
+
+		var snapshot *scopious.Snapshot
+		if len(args) > 0 {
+			snapshot, err = scopious.LoadSnapshot(args[0])
+			if err == nil {
+				scopeName = snapshot.Scope
+			}
+		} else {
+			snapshot, err = scoperInstance.GetScope(scopeName).LatestSnapshot(publicKey)
+			if err == nil && snapshot == nil {
+				log.Fatalf("scope %s has no snapshot that verifies, see scopious freeze", scopeName)
+			}
+		}
+		if err != nil {
+			log.Fatalln("error loading snapshot:", err)
+		}
+
+		err = snapshot.Verify(publicKey)
+		if err != nil {
+			log.Fatalln("snapshot failed verification:", err)
+		}
+
+		signedBy := "unsigned"
+		if len(snapshot.Signature) > 0 {
+			signedBy = "signed by " + keyFingerprint(snapshot.PublicKey)
+		}
+		fmt.Fprintf(os.Stderr, "scope %s frozen %s, %s, %s\n", snapshot.Scope, snapshot.Frozen.Format("2006-01-02 15:04:05 MST"), snapshot.Digest, signedBy)
+
+		scope, ok := scoperInstance.Lookup(scopeName)
+		if !ok {
+			log.Fatalln("scope not found:", scopeName)
+		}
+		deviations := snapshot.Deviations(scope)
+		if len(deviations) == 0 {
+			fmt.Fprintln(os.Stderr, "scope matches the snapshot")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHANGE\tFIELD\tITEM")
+		for _, deviation := range deviations {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", deviation.Change, deviation.Field, deviation.Item)
+		}
+		w.Flush()
+		os.Exit(1)
+	},
+}
+
+// keyFingerprint is the SHA-256 of a public key, shortened for display.
+func keyFingerprint(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return "SHA256:" + hex.EncodeToString(sum[:8])
+}
+
+func init() {
+	RootCmd.AddCommand(VerifyCmd)
+	VerifyCmd.Flags().String("public-key", "", "Ed25519 public key PEM file the snapshot must be signed with")
+}
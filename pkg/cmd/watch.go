@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"github.com/spf13/cobra"
+)
+
+// WatchCmd represents the watch command
+var WatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print scope changes as scope files are edited",
+	Long: `Watch the scope directory and print what changed whenever scope files are
+edited, by hand or by other scopious commands. Invalid edits are reported and
+ignored until they are fixed. For example:
+
+	scopious watch
+	scopious watch --json | jq .
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		watcher, err := scoperInstance.Watch()
+		if err != nil {
+			log.Fatalln("error watching scope:", err)
+		}
+		defer watcher.Close()
+
+		watcher.Subscribe(func(change scopious.ScopeChange) {
+			if asJSON {
+				event := struct {
+					scopious.ScopeChange
+					Error string `json:"error,omitempty"`
+				}{ScopeChange: change}
+				if change.Err != nil {
+					event.Error = change.Err.Error()
+				}
+				data, _ := json.Marshal(event)
+				fmt.Println(string(data))
+				return
+			}
+			printScopeChange(change)
+		})
+
+		log.Printf("watching %s", scoperInstance.Dir())
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
+	},
+}
+
+func printScopeChange(change scopious.ScopeChange) {
+	timestamp := change.Time.Format("15:04:05")
+	if change.Err != nil {
+		fmt.Printf("%s %s invalid, keeping previous scope: %s\n", timestamp, change.Scope, change.Err)
+		return
+	}
+
+	for _, item := range change.Added {
+		fmt.Printf("%s %s +%s\n", timestamp, change.Scope, item)
+	}
+	for _, item := range change.Removed {
+		fmt.Printf("%s %s -%s\n", timestamp, change.Scope, item)
+	}
+	for _, item := range change.ExcludesAdded {
+		fmt.Printf("%s %s +exclude %s\n", timestamp, change.Scope, item)
+	}
+	for _, item := range change.ExcludesRemoved {
+		fmt.Printf("%s %s -exclude %s\n", timestamp, change.Scope, item)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(WatchCmd)
+	WatchCmd.Flags().Bool("json", false, "Print changes as JSON lines")
+}
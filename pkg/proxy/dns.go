@@ -0,0 +1,356 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	DNSActionForwarded = "forwarded"
+	DNSActionFiltered  = "filtered"
+	DNSActionDenied    = "denied"
+	DNSActionFailed    = "failed"
+)
+
+// DNSQueryLog is a record of a query handled by the DNS forwarder.
+type DNSQueryLog struct {
+	Time     time.Time `json:"time"`
+	Client   string    `json:"client"`
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Action   string    `json:"action"`
+	Answers  []string  `json:"answers,omitempty"`
+	Filtered []string  `json:"filtered,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// DNSForwarder forwards queries for in scope names to an upstream DNS server
+// and refuses everything else.
+type DNSForwarder struct {
+	Scope    *scopious.WatchedScope
+	Upstream string
+	// DenyRCode is returned for out of scope names, usually refused or NXDOMAIN.
+	DenyRCode dnsmessage.RCode
+	// FilterAnswers removes A and AAAA answers with excluded addresses.
+	FilterAnswers bool
+	// QueryLog receives a JSON line for every query when set.
+	QueryLog io.Writer
+	Timeout  time.Duration
+
+	logMutex sync.Mutex
+}
+
+// NewDNSForwarder creates a forwarder sending in scope queries to upstream, a
+// host:port address.
+func NewDNSForwarder(scope *scopious.WatchedScope, upstream string) *DNSForwarder {
+	return &DNSForwarder{
+		Scope:     scope,
+		Upstream:  upstream,
+		DenyRCode: dnsmessage.RCodeRefused,
+		Timeout:   5 * time.Second,
+	}
+}
+
+// ListenAndServe serves DNS over UDP and TCP on address.
+func (f *DNSForwarder) ListenAndServe(address string) error {
+	packetConn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		packetConn.Close()
+		return err
+	}
+
+	errs := make(chan error, 2)
+	go func() { errs <- f.ServeUDP(packetConn) }()
+	go func() { errs <- f.ServeTCP(listener) }()
+	err = <-errs
+	packetConn.Close()
+	listener.Close()
+	return err
+}
+
+// ServeUDP answers queries received on conn until it is closed.
+func (f *DNSForwarder) ServeUDP(conn net.PacketConn) error {
+	buf := make([]byte, 64*1024)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		query := append([]byte{}, buf[:n]...)
+		go func() {
+			response := f.handle(query, from.String(), "udp")
+			if response != nil {
+				conn.WriteTo(response, from)
+			}
+		}()
+	}
+}
+
+// ServeTCP answers queries from connections accepted on listener until it is
+// closed.
+func (f *DNSForwarder) ServeTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			for {
+				conn.SetDeadline(time.Now().Add(30 * time.Second))
+				query, err := readDNSTCP(conn)
+				if err != nil {
+					return
+				}
+				response := f.handle(query, conn.RemoteAddr().String(), "tcp")
+				if response == nil || writeDNSTCP(conn, response) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// handle answers a packed query, returning nil when it can't be parsed.
+func (f *DNSForwarder) handle(query []byte, client string, network string) []byte {
+	var request dnsmessage.Message
+	err := request.Unpack(query)
+	if err != nil || len(request.Questions) == 0 {
+		return nil
+	}
+
+	question := request.Questions[0]
+	entry := DNSQueryLog{
+		Time:   time.Now().UTC(),
+		Client: client,
+		Name:   strings.TrimSuffix(strings.ToLower(question.Name.String()), "."),
+		Type:   strings.TrimPrefix(question.Type.String(), "Type"),
+	}
+
+	if len(request.Questions) != 1 {
+		// the whole message is forwarded, so every name in it would have to
+		// be checked, resolvers only ever send one question anyway
+		entry.Action = DNSActionDenied
+		entry.Error = fmt.Sprintf("query has %d questions, only one is allowed", len(request.Questions))
+		f.log(entry)
+		return f.reply(request, dnsmessage.RCodeFormatError)
+	}
+
+	if !f.inScope(question) {
+		entry.Action = DNSActionDenied
+		f.log(entry)
+		return f.reply(request, f.DenyRCode)
+	}
+
+	response, err := f.forward(query, network)
+	if err != nil {
+		entry.Action = DNSActionFailed
+		entry.Error = err.Error()
+		f.log(entry)
+		return f.reply(request, dnsmessage.RCodeServerFailure)
+	}
+
+	entry.Action = DNSActionForwarded
+	response, entry.Answers, entry.Filtered, err = f.filter(response)
+	if err != nil {
+		entry.Action = DNSActionFailed
+		entry.Error = err.Error()
+		f.log(entry)
+		return f.reply(request, dnsmessage.RCodeServerFailure)
+	}
+	if len(entry.Filtered) > 0 {
+		entry.Action = DNSActionFiltered
+	}
+	f.log(entry)
+	return response
+}
+
+// inScope checks the queried name. Reverse lookups are checked against the
+// address being looked up.
+func (f *DNSForwarder) inScope(question dnsmessage.Question) bool {
+	name := strings.TrimSuffix(strings.ToLower(question.Name.String()), ".")
+	matcher := f.Scope.Matcher()
+
+	if question.Type == dnsmessage.TypePTR {
+		addr, ok := netip.AddrFromSlice(reverseNameIP(name))
+		if !ok {
+			return false
+		}
+		return matcher.ContainsAddr(addr)
+	}
+	return matcher.ContainsDomain(name)
+}
+
+func (f *DNSForwarder) forward(query []byte, network string) ([]byte, error) {
+	conn, err := net.DialTimeout(network, f.Upstream, f.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(f.Timeout))
+
+	if network == "tcp" {
+		err = writeDNSTCP(conn, query)
+		if err != nil {
+			return nil, err
+		}
+		return readDNSTCP(conn)
+	}
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 64*1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// filter lists the addresses answered and, when FilterAnswers is set, removes
+// the excluded ones.
+func (f *DNSForwarder) filter(packed []byte) ([]byte, []string, []string, error) {
+	var response dnsmessage.Message
+	err := response.Unpack(packed)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	matcher := f.Scope.Matcher()
+	answers := []string{}
+	filtered := []string{}
+	kept := []dnsmessage.Resource{}
+	for _, answer := range response.Answers {
+		var ip net.IP
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = net.IP(body.A[:])
+		case *dnsmessage.AAAAResource:
+			ip = net.IP(body.AAAA[:])
+		default:
+			kept = append(kept, answer)
+			continue
+		}
+
+		addr, _ := netip.AddrFromSlice(ip)
+		if f.FilterAnswers && matcher.ExcludesAddr(addr) {
+			filtered = append(filtered, ip.String())
+			continue
+		}
+		answers = append(answers, ip.String())
+		kept = append(kept, answer)
+	}
+
+	if len(filtered) == 0 {
+		return packed, answers, filtered, nil
+	}
+	response.Answers = kept
+	packed, err = response.Pack()
+	return packed, answers, filtered, err
+}
+
+func (f *DNSForwarder) reply(request dnsmessage.Message, rcode dnsmessage.RCode) []byte {
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 request.ID,
+			Response:           true,
+			OpCode:             request.OpCode,
+			RecursionDesired:   request.RecursionDesired,
+			RecursionAvailable: true,
+			RCode:              rcode,
+		},
+		Questions: request.Questions,
+	}
+	packed, err := response.Pack()
+	if err != nil {
+		return nil
+	}
+	return packed
+}
+
+func (f *DNSForwarder) log(entry DNSQueryLog) {
+	if entry.Action != DNSActionForwarded {
+		log.Printf("%s DNS %s %s from %s %s", entry.Action, entry.Type, entry.Name, entry.Client, entry.Error)
+	}
+	if f.QueryLog == nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f.logMutex.Lock()
+	defer f.logMutex.Unlock()
+	f.QueryLog.Write(append(data, '\n'))
+}
+
+// reverseNameIP returns the address of an in-addr.arpa or ip6.arpa name.
+func reverseNameIP(name string) net.IP {
+	if labels, ok := strings.CutSuffix(name, ".in-addr.arpa"); ok {
+		parts := strings.Split(labels, ".")
+		if len(parts) != 4 {
+			return nil
+		}
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+		return net.ParseIP(strings.Join(parts, ".")).To4()
+	}
+
+	if labels, ok := strings.CutSuffix(name, ".ip6.arpa"); ok {
+		nibbles := strings.Split(labels, ".")
+		if len(nibbles) != 32 {
+			return nil
+		}
+		hex := strings.Builder{}
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			hex.WriteString(nibbles[i])
+			if i%4 == 0 && i > 0 {
+				hex.WriteString(":")
+			}
+		}
+		return net.ParseIP(hex.String())
+	}
+	return nil
+}
+
+func readDNSTCP(conn net.Conn) ([]byte, error) {
+	length := make([]byte, 2)
+	_, err := io.ReadFull(conn, length)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, binary.BigEndian.Uint16(length))
+	_, err = io.ReadFull(conn, message)
+	return message, err
+}
+
+func writeDNSTCP(conn net.Conn, message []byte) error {
+	if len(message) > 0xffff {
+		return errors.New("DNS message too large")
+	}
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(message)))
+	_, err := conn.Write(append(framed, message...))
+	return err
+}
@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// syncBuffer is a buffer that is safe to write from the forwarder goroutines.
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+// startStubUpstream answers every A query with addrs.
+func startStubUpstream(t *testing.T, addrs ...string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var request dnsmessage.Message
+			if request.Unpack(buf[:n]) != nil {
+				continue
+			}
+
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: request.ID, Response: true},
+				Questions: request.Questions,
+			}
+			for _, addr := range addrs {
+				a := dnsmessage.AResource{}
+				copy(a.A[:], net.ParseIP(addr).To4())
+				response.Answers = append(response.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: request.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &a,
+				})
+			}
+			packed, _ := response.Pack()
+			conn.WriteTo(packed, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func queryDNS(t *testing.T, address string, name string, qtype dnsmessage.Type) dnsmessage.Message {
+	t.Helper()
+	return exchangeDNS(t, address, dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  qtype,
+		Class: dnsmessage.ClassINET,
+	})
+}
+
+func exchangeDNS(t *testing.T, address string, questions ...dnsmessage.Question) dnsmessage.Message {
+	t.Helper()
+	request := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: questions,
+	}
+	packed, err := request.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	conn.Write(packed)
+
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response dnsmessage.Message
+	err = response.Unpack(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestDNSForwarder(t *testing.T) {
+	upstream := startStubUpstream(t, "203.0.113.10", "198.51.100.7")
+
+	scope := newTestScope(t, "example.com", "203.0.113.0/24")
+	forwarder := NewDNSForwarder(scope, upstream)
+	forwarder.FilterAnswers = true
+	queryLog := &syncBuffer{}
+	forwarder.QueryLog = queryLog
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go forwarder.ServeUDP(conn)
+	address := conn.LocalAddr().String()
+
+	response := queryDNS(t, address, "www.example.com.", dnsmessage.TypeA)
+	if response.RCode != dnsmessage.RCodeSuccess || len(response.Answers) != 2 {
+		t.Errorf("in scope query = %v with %d answers", response.RCode, len(response.Answers))
+	}
+
+	response = queryDNS(t, address, "www.example.net.", dnsmessage.TypeA)
+	if response.RCode != dnsmessage.RCodeRefused || len(response.Answers) != 0 {
+		t.Errorf("out of scope query = %v with %d answers", response.RCode, len(response.Answers))
+	}
+
+	response = queryDNS(t, address, "10.113.0.203.in-addr.arpa.", dnsmessage.TypePTR)
+	if response.RCode != dnsmessage.RCodeSuccess {
+		t.Errorf("in scope PTR query = %v", response.RCode)
+	}
+
+	// an out of scope name can't ride along with an in scope one
+	response = exchangeDNS(t, address,
+		dnsmessage.Question{Name: dnsmessage.MustNewName("www.example.com."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+		dnsmessage.Question{Name: dnsmessage.MustNewName("www.example.net."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+	)
+	if response.RCode != dnsmessage.RCodeFormatError || len(response.Answers) != 0 {
+		t.Errorf("multiple question query = %v with %d answers", response.RCode, len(response.Answers))
+	}
+
+	excludeAndReload(t, scope, "198.51.100.7")
+
+	response = queryDNS(t, address, "api.example.com.", dnsmessage.TypeA)
+	if len(response.Answers) != 1 || response.Answers[0].Body.(*dnsmessage.AResource).A != [4]byte{203, 0, 113, 10} {
+		t.Errorf("filtered answers = %v", response.Answers)
+	}
+
+	lines := strings.Split(strings.TrimSpace(queryLog.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("query log has %d lines, want 5", len(lines))
+	}
+	entry := DNSQueryLog{}
+	json.Unmarshal([]byte(lines[3]), &entry)
+	if entry.Action != DNSActionDenied || entry.Error == "" {
+		t.Errorf("multiple question log entry = %+v", entry)
+	}
+	entry = DNSQueryLog{}
+	json.Unmarshal([]byte(lines[4]), &entry)
+	if entry.Name != "api.example.com" || entry.Action != DNSActionFiltered || entry.Filtered[0] != "198.51.100.7" {
+		t.Errorf("query log entry = %+v", entry)
+	}
+}
+
+func TestReverseNameIP(t *testing.T) {
+	tests := map[string]string{
+		"10.113.0.203.in-addr.arpa": "203.0.113.10",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa": "2001:db8::1",
+		"113.0.203.in-addr.arpa": "<nil>",
+		"www.example.com":        "<nil>",
+	}
+	for name, want := range tests {
+		if got := reverseNameIP(name).String(); got != want {
+			t.Errorf("reverseNameIP(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+)
+
+var ErrOutOfScope = errors.New("out of scope")
+var ErrPortNotAllowed = errors.New("port not allowed")
+var ErrUnresolved = errors.New("unable to resolve")
+
+// Guard decides whether a connection to a host and port may be made.
+type Guard struct {
+	Scope *scopious.WatchedScope
+	// Ports limits connections to these ports, any port is allowed when empty.
+	Ports map[int]bool
+	// Resolver is used to check the addresses a host resolves to.
+	Resolver *scopious.DomainResolver
+	// RequireResolvedInScope denies hosts resolving outside IP scope, not
+	// only those resolving to excluded addresses.
+	RequireResolvedInScope bool
+}
+
+// NewGuard creates a guard allowing any port on in scope hosts.
+func NewGuard(scope *scopious.WatchedScope) *Guard {
+	return &Guard{
+		Scope:    scope,
+		Ports:    map[int]bool{},
+		Resolver: scopious.NewDomainResolver(""),
+	}
+}
+
+// Check returns an error wrapping ErrOutOfScope or ErrPortNotAllowed when a
+// connection to host and port is not allowed.
+func (g *Guard) Check(host string, port int) error {
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if host == "" || !g.Scope.Matcher().Contains(host) {
+		return fmt.Errorf("%w: %s", ErrOutOfScope, host)
+	}
+
+	if len(g.Ports) > 0 && !g.Ports[port] {
+		return fmt.Errorf("%w: %d", ErrPortNotAllowed, port)
+	}
+	return nil
+}
+
+// CheckResolved checks host and port like Check, then resolves host and checks
+// the addresses it resolves to. The allowed addresses are returned so callers
+// connect to exactly what was checked.
+func (g *Guard) CheckResolved(ctx context.Context, host string, port int) ([]net.IP, error) {
+	err := g.Check(host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	resolution := g.Resolver.Resolve(ctx, host)
+	switch g.Scope.Matcher().ClassifyResolution(resolution) {
+	case scopious.ResolutionUnresolved:
+		return nil, fmt.Errorf("%w: %s: %s", ErrUnresolved, host, resolution.Error)
+	case scopious.ResolutionExcluded:
+		return nil, fmt.Errorf("%w: %s resolves to an excluded address %s", ErrOutOfScope, host, strings.Join(resolution.Addrs, ","))
+	case scopious.ResolutionOutOfScope:
+		if g.RequireResolvedInScope {
+			return nil, fmt.Errorf("%w: %s resolves outside IP scope %s", ErrOutOfScope, host, strings.Join(resolution.Addrs, ","))
+		}
+	}
+
+	ips := []net.IP{}
+	for _, addr := range resolution.Addrs {
+		ips = append(ips, net.ParseIP(addr))
+	}
+	return ips, nil
+}
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// HTTPProxy is an HTTP forward proxy that only forwards requests to in scope
+// hosts. CONNECT requests are tunnelled once the target has been checked.
+// Request paths aren't checked: scope items are hosts and address space, URLs
+// are added by their hostname, so there are no path rules to check against.
+type HTTPProxy struct {
+	Guard    *Guard
+	Upstream *url.URL
+
+	reverseProxy *httputil.ReverseProxy
+	dialer       net.Dialer
+}
+
+// NewHTTPProxy creates a proxy checking requests with guard. Requests are
+// sent through upstream, like Burp, when it is not nil.
+func NewHTTPProxy(guard *Guard, upstream *url.URL) *HTTPProxy {
+	p := &HTTPProxy{
+		Guard:    guard,
+		Upstream: upstream,
+		dialer:   net.Dialer{Timeout: 30 * time.Second},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	if upstream != nil {
+		transport.Proxy = http.ProxyURL(upstream)
+	}
+
+	p.reverseProxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL = r.In.URL
+			r.Out.Host = r.In.Host
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("error forwarding %s %s: %s", r.Method, r.URL, err)
+			http.Error(w, "scopious: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+	return p
+}
+
+func (p *HTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.serveConnect(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() || r.URL.Host == "" {
+		http.Error(w, "scopious: this is a forward proxy, requests must use an absolute URL", http.StatusBadRequest)
+		return
+	}
+
+	port := r.URL.Port()
+	if port == "" {
+		port = "80"
+		if r.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if !p.allowed(w, r, r.URL.Hostname(), port) {
+		return
+	}
+
+	p.reverseProxy.ServeHTTP(w, r)
+}
+
+func (p *HTTPProxy) serveConnect(w http.ResponseWriter, r *http.Request) {
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, "scopious: invalid CONNECT target "+r.Host, http.StatusBadRequest)
+		return
+	}
+	if !p.allowed(w, r, host, port) {
+		return
+	}
+
+	target, err := p.dialTunnel(r)
+	if err != nil {
+		log.Printf("error connecting to %s: %s", r.Host, err)
+		http.Error(w, "scopious: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		target.Close()
+		http.Error(w, "scopious: CONNECT not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		target.Close()
+		log.Printf("error hijacking connection: %s", err)
+		return
+	}
+
+	_, err = client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	if err != nil {
+		target.Close()
+		client.Close()
+		return
+	}
+
+	if buffered.Reader.Buffered() > 0 {
+		pending, _ := buffered.Reader.Peek(buffered.Reader.Buffered())
+		target.Write(pending)
+	}
+	tunnel(client, target)
+}
+
+// dialTunnel connects to the CONNECT target, through the upstream proxy when
+// there is one.
+func (p *HTTPProxy) dialTunnel(r *http.Request) (net.Conn, error) {
+	if p.Upstream == nil {
+		return p.dialer.DialContext(r.Context(), "tcp", r.Host)
+	}
+
+	upstreamHost := p.Upstream.Host
+	if p.Upstream.Port() == "" {
+		upstreamHost = net.JoinHostPort(p.Upstream.Hostname(), "8080")
+	}
+	conn, err := p.dialer.DialContext(r.Context(), "tcp", upstreamHost)
+	if err != nil {
+		return nil, err
+	}
+
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: r.Host},
+		Host:   r.Host,
+		Header: http.Header{},
+	}
+	if p.Upstream.User != nil {
+		password, _ := p.Upstream.User.Password()
+		connect.SetBasicAuth(p.Upstream.User.Username(), password)
+		connect.Header.Set("Proxy-Authorization", connect.Header.Get("Authorization"))
+		connect.Header.Del("Authorization")
+	}
+	err = connect.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, connect)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("upstream proxy CONNECT failed: %s", response.Status)
+	}
+	if reader.Buffered() > 0 {
+		conn.Close()
+		return nil, fmt.Errorf("upstream proxy sent data before the tunnel was established")
+	}
+	return conn, nil
+}
+
+// allowed checks the target against scope, writing a 403 when it is out of scope.
+func (p *HTTPProxy) allowed(w http.ResponseWriter, r *http.Request, host string, port string) bool {
+	portNumber, _ := strconv.Atoi(port)
+	err := p.Guard.Check(host, portNumber)
+	if err == nil {
+		return true
+	}
+
+	log.Printf("blocked %s %s from %s: %s", r.Method, r.Host, r.RemoteAddr, err)
+	w.Header().Set("X-Scopious-Blocked", "true")
+	http.Error(w, "scopious: blocked, "+err.Error(), http.StatusForbidden)
+	return false
+}
+
+// tunnel copies data between two connections until either side is done.
+func tunnel(client net.Conn, target net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst net.Conn, src net.Conn) {
+		io.Copy(dst, src)
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+	go copyConn(target, client)
+	go copyConn(client, target)
+	<-done
+	<-done
+	client.Close()
+	target.Close()
+}
@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+)
+
+func newTestScope(t *testing.T, items ...string) *scopious.WatchedScope {
+	scoper, err := scopious.Open(t.TempDir(), scopious.WithCreate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoper.GetScope(scopious.DefaultScope).Add(items...)
+	scoper.Save()
+	watcher, err := scoper.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		watcher.Close()
+	})
+	return watcher.Watched(scopious.DefaultScope)
+}
+
+// excludeAndReload excludes an IP address from the scope on disk and waits
+// for the watcher to reload it.
+func excludeAndReload(t *testing.T, scope *scopious.WatchedScope, ip string) {
+	t.Helper()
+	excluded := scopious.NewScopeFromPath(scope.Scope().Path())
+	excluded.Load()
+	excluded.AddExclude(ip)
+	excluded.Save()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !scope.Matcher().ExcludesAddr(netip.MustParseAddr(ip)) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the scope to reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHTTPProxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello "+r.URL.Path)
+	}))
+	defer target.Close()
+	tlsTarget := httptest.NewTLSServer(target.Config.Handler)
+	defer tlsTarget.Close()
+
+	scope := newTestScope(t, "127.0.0.1")
+	proxyServer := httptest.NewServer(NewHTTPProxy(NewGuard(scope), nil))
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	client := tlsTarget.Client()
+	client.Transport.(*http.Transport).Proxy = http.ProxyURL(proxyURL)
+
+	get := func(rawURL string) (int, string) {
+		t.Helper()
+		response, err := client.Get(rawURL)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		return response.StatusCode, string(body)
+	}
+
+	status, body := get(target.URL + "/plain")
+	if status != http.StatusOK || body != "hello /plain" {
+		t.Errorf("in scope HTTP = %d %q", status, body)
+	}
+
+	status, body = get(tlsTarget.URL + "/tunnel")
+	if status != http.StatusOK || body != "hello /tunnel" {
+		t.Errorf("in scope CONNECT = %d %q", status, body)
+	}
+
+	status, _ = get("http://out-of-scope.example.com/")
+	if status != http.StatusForbidden {
+		t.Errorf("out of scope HTTP status = %d, want 403", status)
+	}
+
+	status, body = get("https://out-of-scope.example.com/")
+	if status != 0 {
+		t.Errorf("out of scope CONNECT = %d %q, want error", status, body)
+	}
+
+	excludeAndReload(t, scope, "127.0.0.1")
+
+	status, _ = get(target.URL + "/plain")
+	if status != http.StatusForbidden {
+		t.Errorf("after reload HTTP status = %d, want 403", status)
+	}
+}
+
+func TestGuard_Check(t *testing.T) {
+	guard := NewGuard(newTestScope(t, "example.com", "203.0.113.0/24"))
+	guard.Ports[443] = true
+
+	tests := []struct {
+		host    string
+		port    int
+		wantErr bool
+	}{
+		{host: "www.example.com", port: 443},
+		{host: "WWW.Example.com.", port: 443},
+		{host: "203.0.113.9", port: 443},
+		{host: "www.example.com", port: 80, wantErr: true},
+		{host: "example.com.evil.net", port: 443, wantErr: true},
+		{host: "198.51.100.1", port: 443, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := guard.Check(tt.host, tt.port)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Check(%s, %d) error = %v, wantErr %v", tt.host, tt.port, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	xproxy "golang.org/x/net/proxy"
+)
+
+const (
+	socksVersion5 = 0x05
+
+	socksAuthNone         = 0x00
+	socksAuthNoAcceptable = 0xff
+
+	socksCommandConnect      = 0x01
+	socksCommandBind         = 0x02
+	socksCommandUDPAssociate = 0x03
+
+	socksAddrIPv4   = 0x01
+	socksAddrDomain = 0x03
+	socksAddrIPv6   = 0x04
+
+	socksReplySucceeded           = 0x00
+	socksReplyGeneralFailure      = 0x01
+	socksReplyNotAllowed          = 0x02
+	socksReplyNetworkUnreachable  = 0x03
+	socksReplyHostUnreachable     = 0x04
+	socksReplyConnectionRefused   = 0x05
+	socksReplyCommandNotSupported = 0x07
+	socksReplyAddrNotSupported    = 0x08
+)
+
+// SOCKS5Proxy is a SOCKS5 server that only connects to in scope hosts. Domain
+// names are resolved and the resulting addresses checked before connecting.
+type SOCKS5Proxy struct {
+	Guard *Guard
+	// Upstream is the address of a SOCKS5 proxy to chain to, UDP ASSOCIATE is
+	// not supported when set.
+	Upstream string
+	// UDPTimeout closes idle UDP associations.
+	UDPTimeout time.Duration
+
+	dialer xproxy.ContextDialer
+}
+
+// NewSOCKS5Proxy creates a SOCKS5 proxy checking connections with guard,
+// connecting through the SOCKS5 proxy at upstream when it is not empty.
+func NewSOCKS5Proxy(guard *Guard, upstream string) (*SOCKS5Proxy, error) {
+	p := &SOCKS5Proxy{
+		Guard:      guard,
+		Upstream:   upstream,
+		UDPTimeout: 2 * time.Minute,
+		dialer:     &net.Dialer{Timeout: 30 * time.Second},
+	}
+
+	if upstream != "" {
+		dialer, err := xproxy.SOCKS5("tcp", upstream, nil, &net.Dialer{Timeout: 30 * time.Second})
+		if err != nil {
+			return nil, err
+		}
+		contextDialer, ok := dialer.(xproxy.ContextDialer)
+		if !ok {
+			return nil, errors.New("upstream SOCKS5 dialer does not support contexts")
+		}
+		p.dialer = contextDialer
+	}
+	return p, nil
+}
+
+// ListenAndServe listens on address and serves SOCKS5 connections.
+func (p *SOCKS5Proxy) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return p.Serve(listener)
+}
+
+// Serve accepts SOCKS5 connections from listener until it is closed.
+func (p *SOCKS5Proxy) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *SOCKS5Proxy) handle(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	err := p.negotiate(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	header := make([]byte, 3)
+	_, err = io.ReadFull(conn, header)
+	if err != nil || header[0] != socksVersion5 {
+		conn.Close()
+		return
+	}
+
+	host, port, err := readSOCKSAddr(conn)
+	if err != nil {
+		writeSOCKSReply(conn, socksReplyAddrNotSupported, nil)
+		conn.Close()
+		return
+	}
+
+	switch header[1] {
+	case socksCommandConnect:
+		p.connect(conn, host, port)
+	case socksCommandUDPAssociate:
+		if p.Upstream != "" {
+			writeSOCKSReply(conn, socksReplyCommandNotSupported, nil)
+			conn.Close()
+			return
+		}
+		p.udpAssociate(conn)
+	default:
+		writeSOCKSReply(conn, socksReplyCommandNotSupported, nil)
+		conn.Close()
+	}
+}
+
+// negotiate handles the method selection, only no authentication is supported.
+func (p *SOCKS5Proxy) negotiate(conn net.Conn) error {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		return err
+	}
+	if header[0] != socksVersion5 {
+		return fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	_, err = io.ReadFull(conn, methods)
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(methods, []byte{socksAuthNone}) {
+		conn.Write([]byte{socksVersion5, socksAuthNoAcceptable})
+		return errors.New("no acceptable authentication methods")
+	}
+	_, err = conn.Write([]byte{socksVersion5, socksAuthNone})
+	return err
+}
+
+func (p *SOCKS5Proxy) connect(conn net.Conn, host string, port int) {
+	ips, err := p.Guard.CheckResolved(context.Background(), host, port)
+	if err != nil {
+		log.Printf("blocked SOCKS CONNECT %s from %s: %s", net.JoinHostPort(host, strconv.Itoa(port)), conn.RemoteAddr(), err)
+		reply := byte(socksReplyNotAllowed)
+		if errors.Is(err, ErrUnresolved) {
+			reply = socksReplyHostUnreachable
+		}
+		writeSOCKSReply(conn, reply, nil)
+		conn.Close()
+		return
+	}
+
+	var target net.Conn
+	for _, ip := range ips {
+		target, err = p.dialer.DialContext(context.Background(), "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+		if err == nil {
+			break
+		}
+	}
+	if target == nil {
+		log.Printf("error connecting to %s: %s", net.JoinHostPort(host, strconv.Itoa(port)), err)
+		writeSOCKSReply(conn, dialErrorReply(err), nil)
+		conn.Close()
+		return
+	}
+
+	err = writeSOCKSReply(conn, socksReplySucceeded, target.LocalAddr())
+	if err != nil {
+		target.Close()
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	tunnel(conn, target)
+}
+
+// udpAssociate relays UDP datagrams for the client while the control
+// connection stays open. Datagrams to out of scope destinations are dropped and
+// only replies from destinations the client sent to are relayed back.
+func (p *SOCKS5Proxy) udpAssociate(conn net.Conn) {
+	defer conn.Close()
+
+	localIP := conn.LocalAddr().(*net.TCPAddr).IP
+	relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: localIP})
+	if err != nil {
+		writeSOCKSReply(conn, socksReplyGeneralFailure, nil)
+		return
+	}
+	defer relay.Close()
+
+	err = writeSOCKSReply(conn, socksReplySucceeded, relay.LocalAddr())
+	if err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	go func() {
+		// the association ends when the control connection closes
+		io.Copy(io.Discard, conn)
+		relay.Close()
+	}()
+
+	clientIP := conn.RemoteAddr().(*net.TCPAddr).IP
+	var clientAddr *net.UDPAddr
+	destinations := map[string]bool{}
+
+	buf := make([]byte, 64*1024)
+	for {
+		relay.SetReadDeadline(time.Now().Add(p.UDPTimeout))
+		n, from, err := relay.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		if from.IP.Equal(clientIP) && (clientAddr == nil || from.String() == clientAddr.String()) {
+			clientAddr = from
+			host, port, payload, err := parseSOCKSDatagram(buf[:n])
+			if err != nil {
+				continue
+			}
+
+			ips, err := p.Guard.CheckResolved(context.Background(), host, port)
+			if err != nil {
+				log.Printf("blocked SOCKS UDP %s from %s: %s", net.JoinHostPort(host, strconv.Itoa(port)), from, err)
+				continue
+			}
+
+			destination := &net.UDPAddr{IP: ips[0], Port: port}
+			destinations[destination.String()] = true
+			relay.WriteToUDP(payload, destination)
+			continue
+		}
+
+		if !destinations[from.String()] || clientAddr == nil {
+			continue
+		}
+
+		datagram := bytes.NewBuffer([]byte{0, 0, 0})
+		writeSOCKSAddr(datagram, from)
+		datagram.Write(buf[:n])
+		relay.WriteToUDP(datagram.Bytes(), clientAddr)
+	}
+}
+
+func dialErrorReply(err error) byte {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Timeout() {
+		return socksReplyHostUnreachable
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return socksReplyConnectionRefused
+	}
+	return socksReplyNetworkUnreachable
+}
+
+// readSOCKSAddr reads an address type, address and port.
+func readSOCKSAddr(r io.Reader) (string, int, error) {
+	addrType := make([]byte, 1)
+	_, err := io.ReadFull(r, addrType)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var host string
+	switch addrType[0] {
+	case socksAddrIPv4, socksAddrIPv6:
+		size := net.IPv4len
+		if addrType[0] == socksAddrIPv6 {
+			size = net.IPv6len
+		}
+		ip := make(net.IP, size)
+		_, err = io.ReadFull(r, ip)
+		host = ip.String()
+	case socksAddrDomain:
+		length := make([]byte, 1)
+		_, err = io.ReadFull(r, length)
+		if err != nil {
+			return "", 0, err
+		}
+		domain := make([]byte, length[0])
+		_, err = io.ReadFull(r, domain)
+		host = string(domain)
+	default:
+		return "", 0, fmt.Errorf("unsupported address type %d", addrType[0])
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	port := make([]byte, 2)
+	_, err = io.ReadFull(r, port)
+	if err != nil {
+		return "", 0, err
+	}
+	return host, int(binary.BigEndian.Uint16(port)), nil
+}
+
+// writeSOCKSAddr writes the address type, address and port of addr.
+func writeSOCKSAddr(w *bytes.Buffer, addr net.Addr) {
+	ip := net.IPv4zero
+	port := 0
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip, port = a.IP, a.Port
+	case *net.UDPAddr:
+		ip, port = a.IP, a.Port
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		w.WriteByte(socksAddrIPv4)
+		w.Write(ip4)
+	} else {
+		w.WriteByte(socksAddrIPv6)
+		w.Write(ip.To16())
+	}
+	binary.Write(w, binary.BigEndian, uint16(port))
+}
+
+func writeSOCKSReply(conn net.Conn, reply byte, addr net.Addr) error {
+	buf := bytes.NewBuffer([]byte{socksVersion5, reply, 0})
+	writeSOCKSAddr(buf, addr)
+	_, err := conn.Write(buf.Bytes())
+	return err
+}
+
+// parseSOCKSDatagram parses a UDP request header, fragmented datagrams are not
+// supported.
+func parseSOCKSDatagram(datagram []byte) (string, int, []byte, error) {
+	if len(datagram) < 4 || datagram[2] != 0 {
+		return "", 0, nil, errors.New("invalid or fragmented datagram")
+	}
+
+	reader := bytes.NewReader(datagram[3:])
+	host, port, err := readSOCKSAddr(reader)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return host, port, datagram[len(datagram)-reader.Len():], nil
+}
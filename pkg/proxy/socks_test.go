@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+	xproxy "golang.org/x/net/proxy"
+)
+
+func newTestSOCKS5Proxy(t *testing.T, guard *Guard) string {
+	p, err := NewSOCKS5Proxy(guard, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go p.Serve(listener)
+	return listener.Addr().String()
+}
+
+// offlineResolver resolves from a fixed set of cached resolutions.
+func offlineResolver(resolutions map[string][]string) *scopious.DomainResolver {
+	resolver := scopious.NewDomainResolver("")
+	resolver.Offline = true
+	resolver.Cache = &scopious.ResolutionCache{Resolutions: map[string]scopious.Resolution{}}
+	for domain, addrs := range resolutions {
+		resolver.Cache.Set(scopious.Resolution{Domain: domain, Addrs: addrs})
+	}
+	return resolver
+}
+
+func TestSOCKS5Proxy_Connect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	guard := NewGuard(newTestScope(t, "example.com", "127.0.0.1"))
+	guard.Resolver = offlineResolver(map[string][]string{
+		"www.example.com": {"127.0.0.1"},
+		"cdn.example.com": {"127.0.0.2"},
+	})
+	guard.RequireResolvedInScope = true
+	address := newTestSOCKS5Proxy(t, guard)
+
+	dialer, err := xproxy.SOCKS5("tcp", address, nil, xproxy.Direct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{Dial: dialer.Dial}}
+
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{host: "127.0.0.1"},
+		{host: "www.example.com"},
+		{host: "cdn.example.com", wantErr: true},
+		{host: "out-of-scope.example.net", wantErr: true},
+		{host: "gone.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			response, err := client.Get("http://" + net.JoinHostPort(tt.host, port) + "/")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get(%s) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+			if err == nil {
+				body, _ := io.ReadAll(response.Body)
+				response.Body.Close()
+				if string(body) != "hello" {
+					t.Errorf("Get(%s) body = %q", tt.host, body)
+				}
+			}
+		})
+	}
+}
+
+func TestSOCKS5Proxy_UDPAssociate(t *testing.T) {
+	echo, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, from, err := echo.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteToUDP(buf[:n], from)
+		}
+	}()
+	echoAddr := echo.LocalAddr().(*net.UDPAddr)
+
+	address := newTestSOCKS5Proxy(t, NewGuard(newTestScope(t, "127.0.0.1")))
+	control, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer control.Close()
+
+	control.Write([]byte{socksVersion5, 1, socksAuthNone})
+	control.Write([]byte{socksVersion5, socksCommandUDPAssociate, 0, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+	reply := make([]byte, 2+10)
+	_, err = io.ReadFull(control, reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply[3] != socksReplySucceeded {
+		t.Fatalf("UDP ASSOCIATE reply = %d", reply[3])
+	}
+	_, relayPort, err := readSOCKSAddr(bytes.NewReader(reply[5:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: relayPort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	send := func(addr *net.UDPAddr, payload string) {
+		datagram := bytes.NewBuffer([]byte{0, 0, 0})
+		writeSOCKSAddr(datagram, addr)
+		datagram.WriteString(payload)
+		conn.Write(datagram.Bytes())
+	}
+
+	// out of scope datagrams are dropped, so only the in scope one is echoed
+	send(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: echoAddr.Port}, "blocked")
+	send(echoAddr, "ping")
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, payload, err := parseSOCKSDatagram(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "127.0.0.1" || port != echoAddr.Port || string(payload) != "ping" {
+		t.Errorf("relayed datagram = %s:%d %q", host, port, payload)
+	}
+}
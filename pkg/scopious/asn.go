@@ -0,0 +1,257 @@
+package scopious
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+var asnRegexp = regexp.MustCompile(`(?i)^asn?\s*(\d+)$`)
+
+// ASNRecord is an address range announced by an autonomous system.
+type ASNRecord struct {
+	Start   netip.Addr `json:"start"`
+	End     netip.Addr `json:"end"`
+	ASN     uint32     `json:"asn"`
+	Country string     `json:"country,omitempty"`
+	Org     string     `json:"org"`
+}
+
+// ASNDatabase maps addresses to autonomous systems from a local dataset, so
+// lookups work offline.
+type ASNDatabase struct {
+	// records are sorted by start address and don't overlap.
+	records []ASNRecord
+}
+
+// LoadASNDatabase reads an iptoasn.com TSV file or a GeoLite2 ASN CSV file.
+// Files ending in .gz are decompressed.
+func LoadASNDatabase(path string) (*ASNDatabase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		r = gzipReader
+	}
+	return ParseASNDatabase(r)
+}
+
+// ParseASNDatabase reads ASN data in either of the formats supported by
+// LoadASNDatabase, telling them apart by the first line. iptoasn.com rows hold
+// a start address, end address, AS number, country and description separated
+// by tabs. GeoLite2 rows hold a network, AS number and organisation, after a
+// header line.
+func ParseASNDatabase(r io.Reader) (*ASNDatabase, error) {
+	reader := bufio.NewReader(r)
+	firstLine, err := reader.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	db := &ASNDatabase{}
+	if strings.Contains(strings.SplitN(string(firstLine), "\n", 2)[0], "\t") {
+		err = db.readIPToASN(reader)
+	} else {
+		err = db.readGeoLiteCSV(reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(db.records, func(i, j int) bool {
+		return db.records[i].Start.Less(db.records[j].Start)
+	})
+	return db, nil
+}
+
+func (db *ASNDatabase) readIPToASN(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 5 {
+			continue
+		}
+
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid AS number %q", lineNumber, fields[2])
+		}
+		if asn == 0 {
+			// not routed
+			continue
+		}
+
+		db.records = append(db.records, ASNRecord{
+			Start:   start.Unmap(),
+			End:     end.Unmap(),
+			ASN:     uint32(asn),
+			Country: fields[3],
+			Org:     fields[4],
+		})
+	}
+	return scanner.Err()
+}
+
+func (db *ASNDatabase) readGeoLiteCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		if len(row) < 3 || i == 0 && row[0] == "network" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(row[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		asn, err := strconv.ParseUint(row[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid AS number %q", i+1, row[1])
+		}
+
+		prefix = prefix.Masked()
+		db.records = append(db.records, ASNRecord{
+			Start: prefix.Addr().Unmap(),
+			End:   utils.LastAddr(prefix).Unmap(),
+			ASN:   uint32(asn),
+			Org:   row[2],
+		})
+	}
+	return nil
+}
+
+// Lookup returns the record for the range holding addr.
+func (db *ASNDatabase) Lookup(addr netip.Addr) (ASNRecord, bool) {
+	addr = addr.Unmap()
+	i := sort.Search(len(db.records), func(i int) bool {
+		return addr.Less(db.records[i].Start)
+	})
+	if i == 0 {
+		return ASNRecord{}, false
+	}
+	record := db.records[i-1]
+	if record.End.Less(addr) || record.Start.BitLen() != addr.BitLen() {
+		return ASNRecord{}, false
+	}
+	return record, true
+}
+
+// LookupPrefix returns the records of every autonomous system announcing
+// part of prefix, one per AS.
+func (db *ASNDatabase) LookupPrefix(prefix netip.Prefix) []ASNRecord {
+	prefix = prefix.Masked()
+	first, last := prefix.Addr().Unmap(), utils.LastAddr(prefix).Unmap()
+	i := sort.Search(len(db.records), func(i int) bool {
+		return !db.records[i].End.Less(first)
+	})
+
+	records := []ASNRecord{}
+	seen := map[uint32]bool{}
+	for ; i < len(db.records) && !last.Less(db.records[i].Start); i++ {
+		record := db.records[i]
+		if record.Start.BitLen() == first.BitLen() && !seen[record.ASN] {
+			seen[record.ASN] = true
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// LookupItem returns the autonomous systems of an IP address or CIDR scope
+// item.
+func (db *ASNDatabase) LookupItem(item string) []ASNRecord {
+	prefix, ok := scopeItemPrefix(item)
+	if !ok {
+		return nil
+	}
+	return db.LookupPrefix(prefix)
+}
+
+// Prefixes returns the aggregated address space announced by asn.
+func (db *ASNDatabase) Prefixes(asn uint32) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, record := range db.records {
+		if record.ASN == asn {
+			prefixes = append(prefixes, utils.RangeToPrefixes(record.Start, record.End)...)
+		}
+	}
+	return utils.AggregatePrefixes(prefixes)
+}
+
+// Org returns the organisation name of asn.
+func (db *ASNDatabase) Org(asn uint32) string {
+	for _, record := range db.records {
+		if record.ASN == asn {
+			return record.Org
+		}
+	}
+	return ""
+}
+
+// ParseASN parses scope items like AS64500, returning the AS number.
+func ParseASN(item string) (uint32, bool) {
+	match := asnRegexp.FindStringSubmatch(strings.TrimSpace(item))
+	if match == nil {
+		return 0, false
+	}
+	asn, err := strconv.ParseUint(match[1], 10, 32)
+	return uint32(asn), err == nil
+}
+
+// ExpandASNs replaces AS numbers in items with the prefixes they announce.
+// Notes record the AS each prefix came from. Other items are returned as is.
+func (db *ASNDatabase) ExpandASNs(items []string) ([]string, map[string]string, error) {
+	expanded := []string{}
+	notes := map[string]string{}
+	for _, item := range items {
+		asn, ok := ParseASN(item)
+		if !ok {
+			expanded = append(expanded, item)
+			continue
+		}
+
+		prefixes := db.Prefixes(asn)
+		if len(prefixes) == 0 {
+			return nil, nil, fmt.Errorf("AS%d has no prefixes in the ASN database", asn)
+		}
+		note := strings.TrimSpace(fmt.Sprintf("AS%d %s", asn, db.Org(asn)))
+		for _, prefix := range prefixes {
+			expanded = append(expanded, prefix.String())
+			notes[prefix.String()] = note
+		}
+	}
+	return expanded, notes, nil
+}
@@ -0,0 +1,105 @@
+package scopious
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func loadTestASNDatabase(t *testing.T, name string) *ASNDatabase {
+	t.Helper()
+	db, err := LoadASNDatabase("testdata/asn/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestASNDatabaseLookup(t *testing.T) {
+	for _, name := range []string{"ip2asn-combined.tsv", "GeoLite2-ASN-Blocks-IPv4.csv"} {
+		db := loadTestASNDatabase(t, name)
+		tests := map[string]uint32{
+			"192.0.2.10":     64500,
+			"198.51.100.127": 64500,
+			"198.51.100.128": 64501,
+			"0.1.2.3":        0,
+			"8.8.8.8":        0,
+		}
+		for addr, want := range tests {
+			record, ok := db.Lookup(netip.MustParseAddr(addr))
+			if ok != (want != 0) || record.ASN != want {
+				t.Errorf("%s: Lookup(%s) = AS%d %v, want AS%d", name, addr, record.ASN, ok, want)
+			}
+		}
+	}
+
+	db := loadTestASNDatabase(t, "GeoLite2-ASN-Blocks-IPv4.csv")
+	record, _ := db.Lookup(netip.MustParseAddr("192.0.2.1"))
+	if record.Org != "Example Networks, Inc." {
+		t.Errorf("Org = %q, want quoted CSV field", record.Org)
+	}
+}
+
+func TestASNDatabaseLookupItem(t *testing.T) {
+	db := loadTestASNDatabase(t, "ip2asn-combined.tsv")
+	tests := map[string][]uint32{
+		"198.51.100.0/24":  {64500, 64501},
+		"198.51.100.64/26": {64500},
+		"2001:db8::1":      {64501},
+		"::ffff:192.0.2.1": {64500},
+		"10.0.0.0/8":       {},
+		"example.com":      nil,
+	}
+	for item, want := range tests {
+		var got []uint32
+		for _, record := range db.LookupItem(item) {
+			got = append(got, record.ASN)
+		}
+		if want != nil && len(want) == 0 {
+			want = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("LookupItem(%s) = %v, want %v", item, got, want)
+		}
+	}
+}
+
+func TestASNDatabaseExpandASNs(t *testing.T) {
+	db := loadTestASNDatabase(t, "ip2asn-combined.tsv")
+	items, notes, err := db.ExpandASNs([]string{"AS64500", "example.com", "as64501"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"192.0.2.0/24", "198.51.100.0/25", "203.0.113.0/24", "example.com", "198.51.100.128/25", "2001:db8::/32"}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %v, want %v", items, want)
+	}
+	if notes["203.0.113.0/24"] != "AS64500 EXAMPLE-NET Example Networks" {
+		t.Errorf("note = %q", notes["203.0.113.0/24"])
+	}
+	if _, ok := notes["example.com"]; ok {
+		t.Error("expected no note for example.com")
+	}
+
+	_, _, err = db.ExpandASNs([]string{"AS64999"})
+	if err == nil {
+		t.Error("expected an error for an AS without prefixes")
+	}
+}
+
+func TestParseASN(t *testing.T) {
+	tests := map[string]uint32{
+		"AS64500":   64500,
+		"as64500":   64500,
+		"ASN 13335": 13335,
+		"asdf.com":  0,
+		"64500":     0,
+	}
+	for item, want := range tests {
+		asn, ok := ParseASN(item)
+		if ok != (want != 0) || asn != want {
+			t.Errorf("ParseASN(%q) = %d %v, want %d", item, asn, ok, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package scopious
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+const (
+	AddressClassPublic        = "public"
+	AddressClassPrivate       = "private"
+	AddressClassCGNAT         = "cgnat"
+	AddressClassLoopback      = "loopback"
+	AddressClassLinkLocal     = "link-local"
+	AddressClassDocumentation = "documentation"
+	AddressClassMulticast     = "multicast"
+	AddressClassReserved      = "reserved"
+)
+
+// AddressClasses lists every address class.
+var AddressClasses = []string{
+	AddressClassPublic,
+	AddressClassPrivate,
+	AddressClassCGNAT,
+	AddressClassLoopback,
+	AddressClassLinkLocal,
+	AddressClassDocumentation,
+	AddressClassMulticast,
+	AddressClassReserved,
+}
+
+type classPrefix struct {
+	prefix netip.Prefix
+	class  string
+}
+
+// specialPrefixes are the special purpose ranges from the IANA IPv4 and IPv6
+// registries. Addresses outside them are public, apart from IPv6 outside
+// global unicast space which is reserved.
+var specialPrefixes = []classPrefix{
+	{netip.MustParsePrefix("0.0.0.0/8"), AddressClassReserved},
+	{netip.MustParsePrefix("10.0.0.0/8"), AddressClassPrivate},
+	{netip.MustParsePrefix("100.64.0.0/10"), AddressClassCGNAT},
+	{netip.MustParsePrefix("127.0.0.0/8"), AddressClassLoopback},
+	{netip.MustParsePrefix("169.254.0.0/16"), AddressClassLinkLocal},
+	{netip.MustParsePrefix("172.16.0.0/12"), AddressClassPrivate},
+	{netip.MustParsePrefix("192.0.0.0/24"), AddressClassReserved},
+	{netip.MustParsePrefix("192.0.2.0/24"), AddressClassDocumentation},
+	{netip.MustParsePrefix("192.168.0.0/16"), AddressClassPrivate},
+	{netip.MustParsePrefix("198.18.0.0/15"), AddressClassReserved},
+	{netip.MustParsePrefix("198.51.100.0/24"), AddressClassDocumentation},
+	{netip.MustParsePrefix("203.0.113.0/24"), AddressClassDocumentation},
+	{netip.MustParsePrefix("224.0.0.0/4"), AddressClassMulticast},
+	{netip.MustParsePrefix("240.0.0.0/4"), AddressClassReserved},
+	{netip.MustParsePrefix("::/128"), AddressClassReserved},
+	{netip.MustParsePrefix("::1/128"), AddressClassLoopback},
+	{netip.MustParsePrefix("100::/64"), AddressClassReserved},
+	{netip.MustParsePrefix("2001:db8::/32"), AddressClassDocumentation},
+	{netip.MustParsePrefix("3fff::/20"), AddressClassDocumentation},
+	{netip.MustParsePrefix("fc00::/7"), AddressClassPrivate},
+	{netip.MustParsePrefix("fe80::/10"), AddressClassLinkLocal},
+	{netip.MustParsePrefix("ff00::/8"), AddressClassMulticast},
+}
+
+var globalUnicastIPv6 = netip.MustParsePrefix("2000::/3")
+
+// ClassifyAddr returns the class of an address.
+func ClassifyAddr(addr netip.Addr) string {
+	addr = addr.Unmap()
+	for _, special := range specialPrefixes {
+		if special.prefix.Contains(addr) {
+			return special.class
+		}
+	}
+	if addr.Is6() && !globalUnicastIPv6.Contains(addr) {
+		return AddressClassReserved
+	}
+	return AddressClassPublic
+}
+
+// ClassifyPrefix returns the sorted classes of the addresses in prefix.
+func ClassifyPrefix(prefix netip.Prefix) []string {
+	prefix = prefix.Masked()
+	classes := map[string]bool{}
+	special := []netip.Prefix{}
+	for _, specialPrefix := range specialPrefixes {
+		if specialPrefix.prefix.Overlaps(prefix) {
+			classes[specialPrefix.class] = true
+			special = append(special, specialPrefix.prefix)
+		}
+	}
+
+	rest := utils.SubtractPrefixes([]netip.Prefix{prefix}, special)
+	if prefix.Addr().Is6() {
+		if len(utils.SubtractPrefixes(rest, []netip.Prefix{globalUnicastIPv6})) > 0 {
+			classes[AddressClassReserved] = true
+		}
+		rest = utils.IntersectPrefixes(rest, []netip.Prefix{globalUnicastIPv6})
+	}
+	if len(rest) > 0 {
+		classes[AddressClassPublic] = true
+	}
+	return sortedScopeKeys(classes)
+}
+
+// ClassifyItem returns the classes of an IP address or CIDR scope item, or
+// nil when item is neither.
+func ClassifyItem(item string) []string {
+	prefix, ok := itemPrefix(normalizedScope(item))
+	if !ok {
+		return nil
+	}
+	return ClassifyPrefix(prefix)
+}
+
+// ClassPrefixes returns the address space of a class other than public.
+func ClassPrefixes(class string) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, special := range specialPrefixes {
+		if special.class == class {
+			prefixes = append(prefixes, special.prefix)
+		}
+	}
+	if class == AddressClassReserved {
+		// IPv6 outside global unicast space that no special range classifies,
+		// as in ClassifyPrefix, so each address belongs to a single class
+		classified := []netip.Prefix{globalUnicastIPv6}
+		for _, special := range specialPrefixes {
+			classified = append(classified, special.prefix)
+		}
+		nonGlobal := utils.SubtractPrefixes([]netip.Prefix{netip.MustParsePrefix("::/0")}, classified)
+		prefixes = append(prefixes, nonGlobal...)
+	}
+	return utils.AggregatePrefixes(prefixes)
+}
+
+// NonPublicPrefixes returns the address space that isn't publicly routable.
+func NonPublicPrefixes() []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, class := range AddressClasses {
+		if class != AddressClassPublic {
+			prefixes = append(prefixes, ClassPrefixes(class)...)
+		}
+	}
+	return utils.AggregatePrefixes(prefixes)
+}
+
+// ParseAddressClasses parses a comma separated list of address classes.
+func ParseAddressClasses(classList string) (map[string]bool, error) {
+	classes := map[string]bool{}
+	for _, class := range strings.Split(classList, ",") {
+		class = strings.ToLower(strings.TrimSpace(class))
+		if class == "" {
+			continue
+		}
+		if !isAddressClass(class) {
+			return nil, fmt.Errorf("unknown address class %q, expected one of %s", class, strings.Join(AddressClasses, ", "))
+		}
+		classes[class] = true
+	}
+	return classes, nil
+}
+
+func isAddressClass(class string) bool {
+	for _, addressClass := range AddressClasses {
+		if class == addressClass {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesAddressClasses reports whether any of classes is wanted. No wanted
+// classes matches everything.
+func MatchesAddressClasses(classes []string, wanted map[string]bool) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, class := range classes {
+		if wanted[class] {
+			return true
+		}
+	}
+	return false
+}
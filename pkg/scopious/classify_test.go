@@ -0,0 +1,134 @@
+package scopious
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassifyAddr(t *testing.T) {
+	tests := map[string]string{
+		"8.8.8.8":         AddressClassPublic,
+		"10.1.2.3":        AddressClassPrivate,
+		"100.100.1.1":     AddressClassCGNAT,
+		"127.0.0.1":       AddressClassLoopback,
+		"169.254.169.254": AddressClassLinkLocal,
+		"192.0.2.1":       AddressClassDocumentation,
+		"239.1.1.1":       AddressClassMulticast,
+		"255.255.255.255": AddressClassReserved,
+		"::ffff:10.0.0.1": AddressClassPrivate,
+		"2606:4700::1111": AddressClassPublic,
+		"fd00::1":         AddressClassPrivate,
+		"fe80::1":         AddressClassLinkLocal,
+		"2001:db8::1":     AddressClassDocumentation,
+		"::1":             AddressClassLoopback,
+		"4000::1":         AddressClassReserved,
+	}
+	for addr, want := range tests {
+		if got := ClassifyAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("ClassifyAddr(%s) = %s, want %s", addr, got, want)
+		}
+	}
+}
+
+func TestClassifyItem(t *testing.T) {
+	tests := map[string][]string{
+		"100.64.0.0/11":   {AddressClassCGNAT},
+		"100.0.0.0/8":     {AddressClassCGNAT, AddressClassPublic},
+		"172.16.0.0/11":   {AddressClassPrivate, AddressClassPublic},
+		"0.0.0.0/0":       sortedAddressClasses(),
+		"2001:db8::/33":   {AddressClassDocumentation},
+		"8.8.8.8":         {AddressClassPublic},
+		"www.example.com": nil,
+	}
+	for item, want := range tests {
+		if got := ClassifyItem(item); !reflect.DeepEqual(got, want) {
+			t.Errorf("ClassifyItem(%s) = %v, want %v", item, got, want)
+		}
+	}
+
+	classes, err := ParseAddressClasses("public, CGNAT")
+	if err != nil || !MatchesAddressClasses(ClassifyItem("100.64.0.1"), classes) || MatchesAddressClasses(ClassifyItem("10.0.0.1"), classes) {
+		t.Errorf("ParseAddressClasses() = %v, %v", classes, err)
+	}
+	if _, err := ParseAddressClasses("public,bogon"); err == nil {
+		t.Errorf("ParseAddressClasses(bogon) error = nil")
+	}
+}
+
+func sortedAddressClasses() []string {
+	return []string{
+		AddressClassCGNAT,
+		AddressClassDocumentation,
+		AddressClassLinkLocal,
+		AddressClassLoopback,
+		AddressClassMulticast,
+		AddressClassPrivate,
+		AddressClassPublic,
+		AddressClassReserved,
+	}
+}
+
+func TestProviderRanges(t *testing.T) {
+	ranges := BundledProviderRanges()
+	tests := map[string][]string{
+		"104.16.1.1":      {"cloudflare"},
+		"151.101.0.0/24":  {"fastly"},
+		"104.0.0.0/8":     {"cloudflare", "fastly"},
+		"2606:4700::1111": {"cloudflare"},
+		"8.8.8.8":         {},
+	}
+	for item, want := range tests {
+		if got := ranges.LookupItem(item); !reflect.DeepEqual(got, want) {
+			t.Errorf("LookupItem(%s) = %v, want %v", item, got, want)
+		}
+	}
+
+	var written strings.Builder
+	ranges.Write(&written)
+	reread, err := ParseProviderRanges(strings.NewReader(written.String()))
+	if err != nil || !reflect.DeepEqual(reread, ranges) {
+		t.Errorf("ParseProviderRanges(Write()) = %v, %v", reread, err)
+	}
+}
+
+func TestUpdateProviderRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/aws":
+			w.Write([]byte(`{"prefixes": [{"ip_prefix": "3.0.0.0/15", "service": "AMAZON"}, {"ip_prefix": "3.2.0.0/15", "service": "EC2"}], "ipv6_prefixes": [{"ipv6_prefix": "2600:1f00::/24"}]}`))
+		case "/cloudflare":
+			w.Write([]byte("173.245.48.0/20\n103.21.244.0/22\n"))
+		case "/digitalocean":
+			w.Write([]byte("5.101.96.0/21,NL,NL-NH,Amsterdam,1098 XH\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sources := []ProviderSource{
+		{Provider: "aws", URL: server.URL + "/aws", Parse: parseJSONPrefixes("prefixes.ip_prefix", "ipv6_prefixes.ipv6_prefix")},
+		{Provider: "cloudflare", URL: server.URL + "/cloudflare", Parse: parseLinePrefixes},
+		{Provider: "digitalocean", URL: server.URL + "/digitalocean", Parse: parseLinePrefixes},
+	}
+	ranges, err := UpdateProviderRanges(context.Background(), server.Client(), sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ranges.Prefixes("aws"); len(got) != 2 || got[0].String() != "3.0.0.0/14" {
+		t.Errorf("aws prefixes = %v", got)
+	}
+	if got := ranges.LookupItem("5.101.100.1"); !reflect.DeepEqual(got, []string{"digitalocean"}) {
+		t.Errorf("LookupItem() = %v", got)
+	}
+
+	sources = append(sources, ProviderSource{Provider: "gcp", URL: server.URL + "/missing", Parse: parseLinePrefixes})
+	if _, err := UpdateProviderRanges(context.Background(), server.Client(), sources); err == nil {
+		t.Errorf("UpdateProviderRanges() error = nil for a missing list")
+	}
+}
@@ -0,0 +1,465 @@
+package scopious
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+// commandLabelRegexp matches single label hostnames, which must start with a
+// letter so values like 30s or 80 aren't taken for hosts.
+var commandLabelRegexp = regexp.MustCompile(`^[a-z](?:[a-z0-9-]{0,61}[a-z0-9])?$`)
+
+var commandHostRegexp = regexp.MustCompile(`^(?:[a-z0-9_](?:[a-z0-9_-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]$`)
+
+// ToolSpec describes where a command line tool takes its targets, so only
+// arguments that really are targets get checked. Flag names are given without
+// their leading dashes.
+type ToolSpec struct {
+	// Positional is set when positional arguments are targets. Every flag of
+	// these tools must be known, as the value of an unknown flag would be
+	// taken for a target.
+	Positional bool
+	// TargetFlags take a target as their value.
+	TargetFlags []string
+	// ListFlags take a file holding targets.
+	ListFlags []string
+	// ValueFlags take a value that isn't a target.
+	ValueFlags []string
+	// AttachedFlags are single dash flags whose value may be joined to them,
+	// like -p80 or -T4.
+	AttachedFlags []string
+	// BoolFlags don't take a value.
+	BoolFlags []string
+	// RefuseFlags pick targets that can't be checked, such as random hosts.
+	RefuseFlags []string
+	// CommaSeparated is set when a target argument may hold a comma
+	// separated list of targets.
+	CommaSeparated bool
+	// StdinTargets is set when targets are read from stdin when no target or
+	// list flag is given.
+	StdinTargets bool
+	// Keyword is a placeholder replaced by the tool, like ffuf's FUZZ.
+	Keyword string
+}
+
+// ToolSpecs holds the target arguments of common tools, keyed by command name.
+// Commands without a spec are checked generically.
+var ToolSpecs = map[string]*ToolSpec{
+	"nmap": {
+		Positional:  true,
+		ListFlags:   []string{"iL"},
+		RefuseFlags: []string{"iR", "resume", "b"},
+		ValueFlags: []string{
+			"p", "e", "S", "D", "g", "T", "source-port", "exclude", "excludefile",
+			"exclude-ports", "sI", "oN", "oX", "oG", "oA", "oS", "oM", "stylesheet",
+			"datadir", "script", "script-args", "script-args-file", "script-help",
+			"script-timeout", "top-ports", "port-ratio", "version-intensity",
+			"min-rate", "max-rate", "max-retries", "max-os-tries", "host-timeout",
+			"scan-delay", "max-scan-delay", "min-parallelism", "max-parallelism",
+			"min-hostgroup", "max-hostgroup", "min-rtt-timeout", "max-rtt-timeout",
+			"initial-rtt-timeout", "ttl", "data", "data-string", "data-length",
+			"spoof-mac", "proxies", "dns-servers", "mtu", "ip-options", "scanflags",
+			"servicedb", "versiondb", "stats-every", "nsock-engine",
+		},
+		AttachedFlags: []string{"p", "T", "PS", "PA", "PU", "PY", "PO", "v", "d"},
+		BoolFlags: []string{
+			"sS", "sT", "sA", "sW", "sM", "sU", "sN", "sF", "sX", "sY", "sZ", "sO",
+			"sL", "sn", "sP", "sV", "sC", "sR", "Pn", "PN", "PE", "PP", "PM", "PR",
+			"A", "O", "F", "f", "r", "n", "R", "6", "h", "V", "v", "d", "reason", "open",
+			"packet-trace", "iflist", "traceroute", "osscan-limit", "osscan-guess",
+			"fuzzy", "version-light", "version-all", "version-trace", "script-trace",
+			"script-updatedb", "system-dns", "resolve-all", "unique", "badsum",
+			"adler32", "append-output", "no-stylesheet", "webxml", "privileged",
+			"unprivileged", "send-eth", "send-ip", "defeat-rst-ratelimit",
+			"defeat-icmp-ratelimit", "disable-arp-ping", "discovery-ignore-rst",
+			"randomize-hosts", "noninteractive", "allports", "log-errors",
+			"release-memory", "no-resolve",
+		},
+	},
+	"masscan": {
+		Positional:     true,
+		CommaSeparated: true,
+		TargetFlags:    []string{"range", "ranges"},
+		ListFlags:      []string{"iL", "includefile"},
+		RefuseFlags:    []string{"resume", "readscan"},
+		ValueFlags: []string{
+			"p", "ports", "rate", "max-rate", "c", "conf", "exclude", "excludefile",
+			"oX", "oG", "oJ", "oL", "oB", "oD", "output-filename", "output-format",
+			"e", "adapter", "adapter-ip", "adapter-port", "adapter-mac", "router-mac",
+			"source-ip", "source-port", "source-mac", "wait", "retries", "shard",
+			"seed", "ttl", "connection-timeout", "top-ports", "hello", "hello-file",
+			"hello-string", "hello-timeout", "http-user-agent", "capture",
+			"nocapture", "pcap", "pcap-payloads", "nmap-payloads",
+		},
+		AttachedFlags: []string{"p", "c", "e"},
+		BoolFlags: []string{
+			"sS", "Pn", "n", "v", "vv", "vvv", "d", "echo", "banners", "ping",
+			"packet-trace", "offline", "open", "open-only", "append-output",
+			"pfring", "iflist", "interactive", "nmap", "regress", "selftest",
+			"heartbleed", "ticketbleed", "noreset", "randomize-hosts",
+		},
+	},
+	"nuclei": {
+		CommaSeparated: true,
+		StdinTargets:   true,
+		TargetFlags:    []string{"u", "target"},
+		ListFlags:      []string{"l", "list"},
+	},
+	"httpx": {
+		CommaSeparated: true,
+		StdinTargets:   true,
+		TargetFlags:    []string{"u", "target"},
+		ListFlags:      []string{"l", "list"},
+	},
+	"ffuf": {
+		TargetFlags: []string{"u"},
+		RefuseFlags: []string{"request"},
+		Keyword:     "FUZZ",
+	},
+}
+
+// CommandChange records a target removed from, or narrowed down in, a command.
+type CommandChange struct {
+	Target string `json:"target"`
+	// Source is "argument" or the file and line the target was read from.
+	Source string `json:"source"`
+	// Replacement holds what the target was narrowed down to, if anything.
+	Replacement []string `json:"replacement,omitempty"`
+	Reason      string   `json:"reason"`
+}
+
+// FilteredCommand is a command with its out of scope targets removed.
+type FilteredCommand struct {
+	Args    []string
+	Changes []CommandChange
+	// Targets is how many in scope targets are left.
+	Targets int
+	// TempFiles hold the filtered copies of target list files.
+	TempFiles []string
+	// StdinTargets is set when the tool will read its targets from stdin,
+	// which then needs to be filtered too.
+	StdinTargets bool
+}
+
+// Cleanup removes the filtered target list files.
+func (c *FilteredCommand) Cleanup() {
+	for _, path := range c.TempFiles {
+		os.Remove(path)
+	}
+}
+
+type commandFilter struct {
+	scope     *Scope
+	spec      *ToolSpec
+	included  []netip.Prefix
+	effective []netip.Prefix
+	result    *FilteredCommand
+	// targetFlags is set once a target or list flag is seen.
+	targetFlags bool
+}
+
+// FilterCommand removes out of scope targets from a command line. args[0] is
+// the command, spec describes where it takes its targets. With a nil spec
+// every positional argument that looks like an IP address, CIDR, range, URL or
+// domain is checked. Address space that is only partly in scope is narrowed
+// down to the in scope CIDRs, and target list files are replaced by filtered
+// copies that must be removed with Cleanup.
+func (s *Scope) FilterCommand(args []string, spec *ToolSpec) (*FilteredCommand, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+
+	f := &commandFilter{
+		scope:     s,
+		spec:      spec,
+		included:  s.IncludedPrefixes(),
+		effective: s.EffectivePrefixes(),
+		result:    &FilteredCommand{Args: []string{args[0]}},
+	}
+	err := f.filterArgs(args[1:])
+	if err != nil {
+		f.result.Cleanup()
+		return nil, err
+	}
+	f.result.StdinTargets = spec != nil && spec.StdinTargets && !f.targetFlags
+	return f.result, nil
+}
+
+func (f *commandFilter) filterArgs(args []string) error {
+	flagsDone := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !flagsDone && arg == "--" {
+			flagsDone = true
+			f.result.Args = append(f.result.Args, arg)
+			continue
+		}
+
+		if flagsDone || !strings.HasPrefix(arg, "-") || arg == "-" {
+			if f.spec == nil && !isCommandTarget(arg) || f.spec != nil && !f.spec.Positional {
+				f.result.Args = append(f.result.Args, arg)
+				continue
+			}
+			if f.spec != nil && !f.isPositionalTarget(arg) {
+				return fmt.Errorf("%s is neither a flag nor a target", arg)
+			}
+			f.result.Args = append(f.result.Args, f.filterTargets(arg)...)
+			continue
+		}
+
+		if f.spec == nil {
+			f.result.Args = append(f.result.Args, arg)
+			continue
+		}
+
+		flag, value, hasValue := strings.Cut(arg, "=")
+		name := strings.TrimLeft(flag, "-")
+		isList := slices.Contains(f.spec.ListFlags, name)
+		switch {
+		case slices.Contains(f.spec.RefuseFlags, name):
+			return fmt.Errorf("%s picks targets that can't be checked against scope", flag)
+
+		case isList || slices.Contains(f.spec.TargetFlags, name):
+			f.targetFlags = true
+			if !hasValue {
+				if i+1 == len(args) {
+					f.result.Args = append(f.result.Args, arg)
+					continue
+				}
+				i++
+				value = args[i]
+			}
+
+			values := []string{}
+			if isList {
+				path, err := f.filterList(value)
+				if err != nil {
+					return err
+				}
+				values = append(values, path)
+			} else {
+				values = f.filterTargets(value)
+			}
+
+			// flags left without a target are dropped along with it
+			for _, value := range values {
+				if hasValue {
+					f.result.Args = append(f.result.Args, flag+"="+value)
+				} else {
+					f.result.Args = append(f.result.Args, flag, value)
+				}
+			}
+
+		case slices.Contains(f.spec.ValueFlags, name):
+			f.result.Args = append(f.result.Args, arg)
+			if !hasValue && i+1 < len(args) {
+				i++
+				f.result.Args = append(f.result.Args, args[i])
+			}
+
+		case slices.Contains(f.spec.BoolFlags, name) || f.isAttachedFlag(arg):
+			f.result.Args = append(f.result.Args, arg)
+
+		case f.spec.Positional:
+			return fmt.Errorf("unknown flag %s, its value could be taken for a target", flag)
+
+		default:
+			f.result.Args = append(f.result.Args, arg)
+		}
+	}
+	return nil
+}
+
+// isAttachedFlag reports whether arg is a single dash flag with its value
+// joined to it, like -p80.
+func (f *commandFilter) isAttachedFlag(arg string) bool {
+	if strings.HasPrefix(arg, "--") {
+		return false
+	}
+	for _, name := range f.spec.AttachedFlags {
+		if strings.HasPrefix(arg[1:], name) && len(arg) > len(name)+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTargets checks a target argument, returning the arguments to use in
+// its place.
+func (f *commandFilter) filterTargets(arg string) []string {
+	if f.spec == nil || !f.spec.CommaSeparated {
+		return f.checkTarget(arg, "argument")
+	}
+
+	kept := []string{}
+	for _, target := range strings.Split(arg, ",") {
+		if target != "" {
+			kept = append(kept, f.checkTarget(target, "argument")...)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return []string{strings.Join(kept, ",")}
+}
+
+// filterList writes the in scope targets from a target list to a temporary
+// file, returning its path. The original path is returned when nothing had
+// to be removed.
+func (f *commandFilter) filterList(path string) (string, error) {
+	if path == "-" {
+		return "", errors.New("targets read from stdin can't be checked, pipe them through scopious prune instead")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	changes := len(f.result.Changes)
+	targets := []string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, field := range fields {
+			targets = append(targets, f.checkTarget(field, fmt.Sprintf("%s:%d", path, i+1))...)
+		}
+	}
+	if len(f.result.Changes) == changes {
+		return path, nil
+	}
+
+	file, err := os.CreateTemp("", "scopious-targets-*"+filepath.Ext(path))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	f.result.TempFiles = append(f.result.TempFiles, file.Name())
+
+	_, err = file.WriteString(strings.Join(targets, "\n") + "\n")
+	if err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// checkTarget returns what is left of target once out of scope addresses are
+// removed, recording any change.
+func (f *commandFilter) checkTarget(target string, source string) []string {
+	item := target
+	if f.spec != nil && f.spec.Keyword != "" {
+		item = strings.ReplaceAll(item, f.spec.Keyword, strings.ToLower(f.spec.Keyword))
+	}
+
+	prefixes := commandPrefixes(item)
+	if prefixes == nil {
+		explanation := f.scope.Explain(item)
+		if explanation.InScope {
+			f.result.Targets++
+			return []string{target}
+		}
+		f.result.Changes = append(f.result.Changes, CommandChange{
+			Target: target,
+			Source: source,
+			Reason: explanation.Reason,
+		})
+		return nil
+	}
+
+	inScope := utils.IntersectPrefixes(prefixes, f.effective)
+	if slices.Equal(inScope, utils.AggregatePrefixes(prefixes)) {
+		f.result.Targets++
+		return []string{target}
+	}
+
+	change := CommandChange{Target: target, Source: source}
+	for _, prefix := range inScope {
+		change.Replacement = append(change.Replacement, utils.PrefixString(prefix))
+	}
+	switch {
+	case len(inScope) > 0:
+		change.Reason = "narrowed to the in scope addresses"
+	case len(utils.IntersectPrefixes(prefixes, f.included)) > 0:
+		change.Reason = "excluded"
+	default:
+		change.Reason = "not in IP scope"
+	}
+	f.result.Changes = append(f.result.Changes, change)
+	f.result.Targets += len(change.Replacement)
+	return change.Replacement
+}
+
+// commandPrefixes parses an address, CIDR or range target, returning nil for
+// anything else.
+func commandPrefixes(target string) []netip.Prefix {
+	start, end, err := utils.ParseRange(target)
+	if err == nil {
+		return utils.RangeToPrefixes(start, end)
+	}
+	prefix, ok := itemPrefix(target)
+	if ok {
+		return []netip.Prefix{prefix}
+	}
+	return nil
+}
+
+// isPositionalTarget reports whether a positional argument of a tool known to
+// take targets that way parses as IP addresses, CIDRs, ranges or hostnames.
+func (f *commandFilter) isPositionalTarget(arg string) bool {
+	if !f.spec.CommaSeparated {
+		return isPositionalTarget(arg)
+	}
+	for _, target := range strings.Split(arg, ",") {
+		if target != "" && !isPositionalTarget(target) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPositionalTarget(arg string) bool {
+	if commandPrefixes(arg) != nil {
+		return true
+	}
+	host := strings.TrimSuffix(strings.ToLower(arg), ".")
+	return commandHostRegexp.MatchString(host) || commandLabelRegexp.MatchString(host)
+}
+
+// isCommandTarget reports whether a generic command argument looks like a
+// target. Arguments naming existing files are left alone.
+func isCommandTarget(arg string) bool {
+	if commandPrefixes(arg) != nil {
+		return true
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return false
+	}
+
+	if strings.Contains(arg, "://") {
+		parsedURL, err := url.Parse(arg)
+		return err == nil && parsedURL.Hostname() != ""
+	}
+
+	host := arg
+	splitHost, _, err := net.SplitHostPort(arg)
+	if err == nil {
+		host = splitHost
+	}
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return commandHostRegexp.MatchString(host) && hasPublicSuffix(host)
+}
@@ -0,0 +1,159 @@
+package scopious
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScope_FilterCommand(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.AddExclude("203.0.113.128/25", "admin.example.com")
+	s.Add("203.0.113.0/24", "example.com")
+
+	tests := []struct {
+		name        string
+		args        []string
+		spec        *ToolSpec
+		wantArgs    []string
+		wantChanges int
+		wantStdin   bool
+		wantErr     bool
+	}{
+		{
+			name:        "nmap",
+			args:        []string{"nmap", "-sV", "-p", "443", "-oX", "out.xml", "203.0.113.0/24", "10.9.9.9", "www.example.com"},
+			spec:        ToolSpecs["nmap"],
+			wantArgs:    []string{"nmap", "-sV", "-p", "443", "-oX", "out.xml", "203.0.113.0/25", "www.example.com"},
+			wantChanges: 2,
+		},
+		{
+			name:     "nmap range",
+			args:     []string{"nmap", "203.0.113.1-20"},
+			spec:     ToolSpecs["nmap"],
+			wantArgs: []string{"nmap", "203.0.113.1-20"},
+		},
+		{
+			name:        "nmap flag values",
+			args:        []string{"nmap", "-sV", "--exclude-ports", "80", "--script-timeout", "30s", "-T4", "-p1-1024", "203.0.113.5", "10.0.0.5"},
+			spec:        ToolSpecs["nmap"],
+			wantArgs:    []string{"nmap", "-sV", "--exclude-ports", "80", "--script-timeout", "30s", "-T4", "-p1-1024", "203.0.113.5"},
+			wantChanges: 1,
+		},
+		{
+			name:     "nmap verbosity and debugging",
+			args:     []string{"nmap", "-v", "-d", "-vv", "-d2", "203.0.113.5"},
+			spec:     ToolSpecs["nmap"],
+			wantArgs: []string{"nmap", "-v", "-d", "-vv", "-d2", "203.0.113.5"},
+		},
+		{
+			name:    "nmap unknown flag",
+			args:    []string{"nmap", "--new-flag", "30s", "203.0.113.5"},
+			spec:    ToolSpecs["nmap"],
+			wantErr: true,
+		},
+		{
+			name:    "nmap positional that isn't a target",
+			args:    []string{"nmap", "203.0.113.5", "30s"},
+			spec:    ToolSpecs["nmap"],
+			wantErr: true,
+		},
+		{
+			name:    "nmap random targets",
+			args:    []string{"nmap", "-iR", "100"},
+			spec:    ToolSpecs["nmap"],
+			wantErr: true,
+		},
+		{
+			name:        "masscan comma separated",
+			args:        []string{"masscan", "--rate", "1000", "203.0.113.5,198.51.100.0/24", "--range=10.0.0.1"},
+			spec:        ToolSpecs["masscan"],
+			wantArgs:    []string{"masscan", "--rate", "1000", "203.0.113.5"},
+			wantChanges: 2,
+		},
+		{
+			name:        "masscan flag values",
+			args:        []string{"masscan", "-p80", "--connection-timeout", "5", "10.0.0.5", "203.0.113.5"},
+			spec:        ToolSpecs["masscan"],
+			wantArgs:    []string{"masscan", "-p80", "--connection-timeout", "5", "203.0.113.5"},
+			wantChanges: 1,
+		},
+		{
+			name:        "nuclei",
+			args:        []string{"nuclei", "-u", "https://admin.example.com", "-u", "https://www.example.com", "-t", "http/"},
+			spec:        ToolSpecs["nuclei"],
+			wantArgs:    []string{"nuclei", "-u", "https://www.example.com", "-t", "http/"},
+			wantChanges: 1,
+		},
+		{
+			name:      "httpx stdin",
+			args:      []string{"httpx", "-silent"},
+			spec:      ToolSpecs["httpx"],
+			wantArgs:  []string{"httpx", "-silent"},
+			wantStdin: true,
+		},
+		{
+			name:     "ffuf keyword",
+			args:     []string{"ffuf", "-w", "words.txt", "-u", "https://FUZZ.example.com/"},
+			spec:     ToolSpecs["ffuf"],
+			wantArgs: []string{"ffuf", "-w", "words.txt", "-u", "https://FUZZ.example.com/"},
+		},
+		{
+			name:        "generic",
+			args:        []string{"curl", "-s", "https://example.net/", "-o", "page.html", "example.com:8443"},
+			wantArgs:    []string{"curl", "-s", "-o", "page.html", "example.com:8443"},
+			wantChanges: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.FilterCommand(tt.args, tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FilterCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer got.Cleanup()
+			if !reflect.DeepEqual(got.Args, tt.wantArgs) {
+				t.Errorf("FilterCommand() args = %v, want %v", got.Args, tt.wantArgs)
+			}
+			if len(got.Changes) != tt.wantChanges {
+				t.Errorf("FilterCommand() changes = %+v, want %d", got.Changes, tt.wantChanges)
+			}
+			if got.StdinTargets != tt.wantStdin {
+				t.Errorf("FilterCommand() StdinTargets = %v, want %v", got.StdinTargets, tt.wantStdin)
+			}
+		})
+	}
+}
+
+func TestScope_FilterCommand_ListFile(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.Add("203.0.113.0/24")
+
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	os.WriteFile(path, []byte("# targets\n203.0.113.5 10.9.9.9\n\n198.51.100.7\n"), 0644)
+
+	got, err := s.FilterCommand([]string{"nmap", "-iL", path}, ToolSpecs["nmap"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Args[2] == path || len(got.TempFiles) != 1 {
+		t.Fatalf("FilterCommand() args = %v, want filtered list file", got.Args)
+	}
+	data, _ := os.ReadFile(got.Args[2])
+	if strings.TrimSpace(string(data)) != "203.0.113.5" {
+		t.Errorf("filtered list = %q", data)
+	}
+	if len(got.Changes) != 2 || got.Changes[0].Source != path+":2" {
+		t.Errorf("FilterCommand() changes = %+v", got.Changes)
+	}
+
+	got.Cleanup()
+	if _, err := os.Stat(got.TempFiles[0]); !os.IsNotExist(err) {
+		t.Errorf("Cleanup() left %s", got.TempFiles[0])
+	}
+}
@@ -0,0 +1,185 @@
+package scopious
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// ConflictOverlap is address space or a domain included by both scopes.
+	ConflictOverlap = "overlap"
+	// ConflictContradiction is something one scope includes and the other
+	// excludes.
+	ConflictContradiction = "contradiction"
+)
+
+// ScopeConflict is address space or a domain that two scopes disagree on or
+// share. For contradictions Scope is the scope including it and OtherScope the
+// scope excluding it.
+type ScopeConflict struct {
+	Kind       string `json:"kind"`
+	Scope      string `json:"scope"`
+	OtherScope string `json:"other_scope"`
+	// Overlap is the shared address space or domain.
+	Overlap   string `json:"overlap"`
+	Item      string `json:"item"`
+	OtherItem string `json:"other_item"`
+}
+
+// Conflicts compares every pair of scopes, reporting overlapping includes and
+// includes that another scope excludes.
+func (scoper *Scoper) Conflicts() []ScopeConflict {
+	names := scoper.Names()
+	conflicts := []ScopeConflict{}
+	for i, name := range names {
+		for _, other := range names[i+1:] {
+			conflicts = append(conflicts, CompareScopes(name, scoper.scopes[name], other, scoper.scopes[other])...)
+		}
+	}
+	return conflicts
+}
+
+// CompareScopes reports the conflicts between two scopes.
+func CompareScopes(name string, scope *Scope, otherName string, other *Scope) []ScopeConflict {
+	conflicts := []ScopeConflict{}
+	conflicts = append(conflicts, prefixOverlaps(name, scope, otherName, other)...)
+	conflicts = append(conflicts, domainOverlaps(name, scope, otherName, other)...)
+	conflicts = append(conflicts, contradictions(name, scope, otherName, other)...)
+	conflicts = append(conflicts, contradictions(otherName, other, name, scope)...)
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+		return conflicts[i].Overlap < conflicts[j].Overlap
+	})
+	return conflicts
+}
+
+// includedItemPrefixes maps each included IP address and CIDR to its prefix.
+func (s *Scope) includedItemPrefixes() map[string]netip.Prefix {
+	prefixes := map[string]netip.Prefix{}
+	for _, scopeMap := range []map[string]bool{s.ipv4, s.ipv6} {
+		for item := range scopeMap {
+			prefix, ok := itemPrefix(item)
+			if ok {
+				prefixes[item] = prefix
+			}
+		}
+	}
+	return prefixes
+}
+
+func prefixOverlaps(name string, scope *Scope, otherName string, other *Scope) []ScopeConflict {
+	conflicts := []ScopeConflict{}
+	effective := utils.IntersectPrefixes(scope.EffectivePrefixes(), other.EffectivePrefixes())
+	if len(effective) == 0 {
+		return conflicts
+	}
+
+	otherPrefixes := other.includedItemPrefixes()
+	for item, prefix := range scope.includedItemPrefixes() {
+		for otherItem, otherPrefix := range otherPrefixes {
+			shared := utils.IntersectPrefixes([]netip.Prefix{prefix}, []netip.Prefix{otherPrefix})
+			for _, overlap := range utils.IntersectPrefixes(shared, effective) {
+				conflicts = append(conflicts, ScopeConflict{
+					Kind:       ConflictOverlap,
+					Scope:      name,
+					OtherScope: otherName,
+					Overlap:    utils.PrefixString(overlap),
+					Item:       item,
+					OtherItem:  otherItem,
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// domainOverlap returns the names covered by both domains. Subdomains of an
+// included domain's registrable domain are in scope, so domains sharing one
+// overlap on its subdomains.
+func domainOverlap(domain string, other string) string {
+	switch {
+	case domain == other || strings.HasSuffix(domain, "."+other):
+		return domain
+	case strings.HasSuffix(other, "."+domain):
+		return other
+	}
+
+	root, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return ""
+	}
+	otherRoot, err := publicsuffix.EffectiveTLDPlusOne(other)
+	if err != nil || root != otherRoot {
+		return ""
+	}
+	return "*." + root
+}
+
+func domainOverlaps(name string, scope *Scope, otherName string, other *Scope) []ScopeConflict {
+	conflicts := []ScopeConflict{}
+	for domain := range scope.domains {
+		for otherDomain := range other.domains {
+			overlap := domainOverlap(domain, otherDomain)
+			if overlap == "" || !scope.Explain(overlap).InScope || !other.Explain(overlap).InScope {
+				// excluded by either scope, reported as a contradiction
+				continue
+			}
+			conflicts = append(conflicts, ScopeConflict{
+				Kind:       ConflictOverlap,
+				Scope:      name,
+				OtherScope: otherName,
+				Overlap:    overlap,
+				Item:       domain,
+				OtherItem:  otherDomain,
+			})
+		}
+	}
+	return conflicts
+}
+
+// contradictions reports what scope includes and other excludes.
+func contradictions(name string, scope *Scope, otherName string, other *Scope) []ScopeConflict {
+	conflicts := []ScopeConflict{}
+	includedPrefixes := scope.includedItemPrefixes()
+	effective := scope.EffectivePrefixes()
+
+	for exclude := range other.excludes {
+		excludePrefix, ok := itemPrefix(exclude)
+		if !ok {
+			explanation := scope.Explain(exclude)
+			if explanation.InScope {
+				conflicts = append(conflicts, ScopeConflict{
+					Kind:       ConflictContradiction,
+					Scope:      name,
+					OtherScope: otherName,
+					Overlap:    explanation.Normalized,
+					Item:       explanation.Rule,
+					OtherItem:  exclude,
+				})
+			}
+			continue
+		}
+
+		for item, prefix := range includedPrefixes {
+			shared := utils.IntersectPrefixes([]netip.Prefix{prefix}, []netip.Prefix{excludePrefix})
+			for _, overlap := range utils.IntersectPrefixes(shared, effective) {
+				conflicts = append(conflicts, ScopeConflict{
+					Kind:       ConflictContradiction,
+					Scope:      name,
+					OtherScope: otherName,
+					Overlap:    utils.PrefixString(overlap),
+					Item:       item,
+					OtherItem:  exclude,
+				})
+			}
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,31 @@
+package scopious
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScoper_Conflicts(t *testing.T) {
+	scoper := openScoper(t, t.TempDir())
+	external := scoper.GetScope("external")
+	external.Add("203.0.113.0/24", "198.51.100.7", "www.example.com", "example.org")
+	external.AddExclude("10.0.0.0/16", "203.0.113.128/25")
+
+	internal := scoper.GetScope("internal")
+	internal.Add("10.0.0.0/15", "203.0.113.0/23", "api.example.com", "example.net")
+	internal.AddExclude("198.51.100.0/24", "example.org")
+
+	want := []ScopeConflict{
+		{Kind: ConflictContradiction, Scope: "internal", OtherScope: "external", Overlap: "10.0.0.0/16", Item: "10.0.0.0/15", OtherItem: "10.0.0.0/16"},
+		{Kind: ConflictContradiction, Scope: "external", OtherScope: "internal", Overlap: "198.51.100.7", Item: "198.51.100.7", OtherItem: "198.51.100.0/24"},
+		{Kind: ConflictContradiction, Scope: "internal", OtherScope: "external", Overlap: "203.0.113.128/25", Item: "203.0.112.0/23", OtherItem: "203.0.113.128/25"},
+		{Kind: ConflictContradiction, Scope: "external", OtherScope: "internal", Overlap: "example.org", Item: "example.org", OtherItem: "example.org"},
+		{Kind: ConflictOverlap, Scope: "external", OtherScope: "internal", Overlap: "*.example.com", Item: "www.example.com", OtherItem: "api.example.com"},
+		{Kind: ConflictOverlap, Scope: "external", OtherScope: "internal", Overlap: "203.0.113.0/25", Item: "203.0.113.0/24", OtherItem: "203.0.112.0/23"},
+	}
+
+	got := scoper.Conflicts()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Conflicts() =\n%+v\nwant\n%+v", got, want)
+	}
+}
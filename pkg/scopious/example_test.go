@@ -0,0 +1,88 @@
+package scopious_test
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+
+	"github.com/analog-substance/scopious/pkg/scopious"
+)
+
+func ExampleOpen() {
+	dir, err := os.MkdirTemp("", "scope")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	scoper, err := scopious.Open(dir, scopious.WithCreate(), scopious.WithDefaultScope("external"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	scope := scoper.GetScope("external")
+	scope.Add("example.com", "203.0.113.0/24")
+	scope.AddExclude("203.0.113.128/25")
+	err = scoper.Save()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reopened, err := scopious.Open(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	external, _ := reopened.Lookup("external")
+	fmt.Println(reopened.Names())
+	fmt.Println(external.AllIPs(), external.AllDomains(), external.Excludes())
+	// Output:
+	// [external]
+	// [203.0.113.0/24] [example.com] [203.0.113.128/25]
+}
+
+func ExampleScope_Compile() {
+	scope := scopious.NewScopeFromPath("")
+	scope.Add("example.com", "203.0.113.0/24")
+	scope.AddExclude("admin.example.com", "203.0.113.128/25")
+
+	matcher := scope.Compile()
+	fmt.Println(matcher.Contains("https://www.example.com/login"))
+	fmt.Println(matcher.Contains("admin.example.com"))
+	fmt.Println(matcher.ContainsAddr(netip.MustParseAddr("203.0.113.10")))
+	fmt.Println(matcher.ContainsPrefix(netip.MustParsePrefix("203.0.113.0/24")))
+	fmt.Println(matcher.EffectivePrefixes())
+	// Output:
+	// true
+	// false
+	// true
+	// false
+	// [203.0.113.0/25]
+}
+
+func ExampleMatcher_Explain() {
+	scope := scopious.NewScopeFromPath("")
+	scope.Add("example.com")
+	scope.AddExclude("admin.example.com")
+
+	matcher := scope.Compile()
+	for _, item := range []string{"www.example.com", "vpn.admin.example.com", "example.net"} {
+		explanation := matcher.Explain(item)
+		fmt.Printf("%s %v %q %s\n", item, explanation.InScope, explanation.Reason, explanation.Rule)
+	}
+	// Output:
+	// www.example.com true "subdomain of an in scope domain" example.com
+	// vpn.admin.example.com false "parent domain excluded" admin.example.com
+	// example.net false "not in domain scope"
+}
+
+func ExampleScope_Expand() {
+	scope := scopious.NewScopeFromPath("")
+	scope.Add("192.0.2.0/30")
+	scope.AddExclude("192.0.2.2")
+
+	fmt.Println(len(scope.Expand(scopious.ExpandOptions{})))
+	fmt.Println(len(scope.Expand(scopious.ExpandOptions{NetworkAndBroadcast: true})))
+	// Output:
+	// 1
+	// 3
+}
@@ -0,0 +1,44 @@
+package scopious
+
+const (
+	ExplainKindIP      = "ip"
+	ExplainKindCIDR    = "cidr"
+	ExplainKindDomain  = "domain"
+	ExplainKindInvalid = "invalid"
+)
+
+// Explanation describes why an item is or is not in scope.
+type Explanation struct {
+	Item       string `json:"item"`
+	Normalized string `json:"normalized"`
+	Kind       string `json:"kind"`
+	InScope    bool   `json:"in_scope"`
+	Reason     string `json:"reason"`
+	// Rule is the include or exclude that decided the verdict, if any.
+	Rule string `json:"rule,omitempty"`
+}
+
+// Explain reports whether item is in scope along with the rule responsible.
+// Excludes are checked first as they take precedence. Compile the scope
+// instead when explaining many items.
+func (s *Scope) Explain(item string) Explanation {
+	return s.Compile().Explain(item)
+}
+
+// Excluded reports whether the item is out of scope because of an exclude,
+// rather than because nothing includes it.
+func (e Explanation) Excluded() bool {
+	switch e.Reason {
+	case "excluded", "partly excluded", "parent domain excluded", "resolves to an excluded address":
+		return true
+	}
+	return false
+}
+
+// String is the reason followed by the rule responsible, if any.
+func (e Explanation) String() string {
+	if e.Rule == "" {
+		return e.Reason
+	}
+	return e.Reason + ": " + e.Rule
+}
@@ -0,0 +1,52 @@
+package scopious
+
+import "testing"
+
+func TestScope_Explain(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.AddExclude("admin.example.com", "203.0.113.128/26")
+	s.Add("example.com", "203.0.113.0/24", "192.0.2.5")
+
+	tests := []struct {
+		item        string
+		wantInScope bool
+		wantReason  string
+		wantRule    string
+	}{
+		{item: "example.com", wantInScope: true, wantReason: "included", wantRule: "example.com"},
+		{item: "https://www.example.com/login", wantInScope: true, wantReason: "subdomain of an in scope domain", wantRule: "example.com"},
+		{item: "admin.example.com", wantReason: "excluded", wantRule: "admin.example.com"},
+		{item: "api.admin.example.com", wantReason: "parent domain excluded", wantRule: "admin.example.com"},
+		{item: "example.net", wantReason: "not in domain scope"},
+		{item: "203.0.113.7", wantInScope: true, wantReason: "included", wantRule: "203.0.113.0/24"},
+		{item: "192.0.2.5", wantInScope: true, wantReason: "included", wantRule: "192.0.2.5"},
+		{item: "203.0.113.130", wantReason: "excluded", wantRule: "203.0.113.128/26"},
+		{item: "203.0.113.0/24", wantReason: "partly excluded", wantRule: "203.0.113.128/26"},
+		{item: "203.0.113.0/25", wantInScope: true, wantReason: "included", wantRule: "203.0.113.0/24"},
+		{item: "198.51.100.1", wantReason: "not in IP scope"},
+		{item: "", wantReason: "not a valid IP address, CIDR, URL or domain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.item, func(t *testing.T) {
+			got := s.Explain(tt.item)
+			if got.InScope != tt.wantInScope || got.Reason != tt.wantReason || got.Rule != tt.wantRule {
+				t.Errorf("Explain(%s) = %+v, want %v %q %q", tt.item, got, tt.wantInScope, tt.wantReason, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestScope_Remove(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.Add("example.com", "203.0.113.0/24")
+	s.AddExclude("admin.example.com")
+
+	s.Remove("203.0.113.0/24", "https://example.com/")
+	s.RemoveExclude("admin.example.com")
+	if s.IsInScope("203.0.113.7") || s.IsInScope("www.example.com") {
+		t.Errorf("removed items still in scope")
+	}
+	if len(s.excludes) != 0 || s.Explain("admin.example.com").Reason != "not in domain scope" {
+		t.Errorf("Excludes = %v", s.excludes)
+	}
+}
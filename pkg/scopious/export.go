@@ -0,0 +1,414 @@
+package scopious
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+const (
+	ExportFormatBurp    = "burp"
+	ExportFormatZAP     = "zap"
+	ExportFormatNmap    = "nmap"
+	ExportFormatMasscan = "masscan"
+	ExportFormatNuclei  = "nuclei"
+	// ExportFormatMatcher and ExportFormatMatcherBinary encode the compiled
+	// Matcher for agents checking scope without the scope files.
+	ExportFormatMatcher       = "matcher"
+	ExportFormatMatcherBinary = "matcher-binary"
+)
+
+// ExportFile is a single file produced by an export.
+type ExportFile struct {
+	Name    string
+	Content []byte
+}
+
+// ExportResult holds the files produced by an export along with warnings for
+// scope items the target format could not express.
+type ExportResult struct {
+	Files    []ExportFile
+	Warnings []string
+}
+
+// Name returns the scope name, which is the name of its directory.
+func (s *Scope) Name() string {
+	return filepath.Base(s.path)
+}
+
+// Export renders the scope in the given tool format.
+func (s *Scope) Export(format string) (*ExportResult, error) {
+	switch format {
+	case ExportFormatBurp:
+		return s.exportBurp()
+	case ExportFormatZAP:
+		return s.exportZAP()
+	case ExportFormatNmap, ExportFormatMasscan, ExportFormatNuclei:
+		return s.exportScanner(format)
+	case ExportFormatMatcher, ExportFormatMatcherBinary:
+		return s.exportMatcher(format)
+	}
+	return nil, fmt.Errorf("unknown export format: %s", format)
+}
+
+// domainRules returns the in scope root domains, which include all of their
+// subdomains, and the in scope domains not covered by any of those root domains.
+func (s *Scope) domainRules() (wildcards []string, exact []string) {
+	matcher := s.Compile()
+	wildcards = matcher.rootDomains
+	for _, domain := range s.AllDomains() {
+		covered := false
+		for _, rootDomain := range wildcards {
+			if domain == rootDomain || strings.HasSuffix(domain, "."+rootDomain) {
+				covered = true
+				break
+			}
+		}
+		if !covered && matcher.ContainsDomain(domain) {
+			exact = append(exact, domain)
+		}
+	}
+	return
+}
+
+// hostRegexp returns an anchored regular expression matching host and, when
+// withSubdomains is set, any of its subdomains.
+func hostRegexp(host string, withSubdomains bool) string {
+	hostPattern := regexp.QuoteMeta(host)
+	if withSubdomains {
+		return "^(?:[^.]+\\.)*" + hostPattern + "$"
+	}
+	return "^" + hostPattern + "$"
+}
+
+type burpScopeEntry struct {
+	Enabled  bool   `json:"enabled"`
+	File     string `json:"file"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+type burpProjectOptions struct {
+	Target struct {
+		Scope struct {
+			AdvancedMode bool             `json:"advanced_mode"`
+			Exclude      []burpScopeEntry `json:"exclude"`
+			Include      []burpScopeEntry `json:"include"`
+		} `json:"scope"`
+	} `json:"target"`
+}
+
+func newBurpScopeEntry(host string) burpScopeEntry {
+	return burpScopeEntry{
+		Enabled:  true,
+		File:     "^/.*",
+		Host:     host,
+		Port:     "^[0-9]+$",
+		Protocol: "any",
+	}
+}
+
+// burpHost returns the Burp host field for an IP address or CIDR. Burp accepts
+// IP ranges in the host field directly, single addresses are matched by regex.
+func burpHost(ipOrCIDR string) string {
+	if strings.Contains(ipOrCIDR, "/") {
+		return ipOrCIDR
+	}
+	return hostRegexp(ipOrCIDR, false)
+}
+
+func (s *Scope) exportBurp() (*ExportResult, error) {
+	options := burpProjectOptions{}
+	options.Target.Scope.AdvancedMode = true
+	options.Target.Scope.Include = []burpScopeEntry{}
+	options.Target.Scope.Exclude = []burpScopeEntry{}
+
+	wildcards, exact := s.domainRules()
+	for _, domain := range wildcards {
+		options.Target.Scope.Include = append(options.Target.Scope.Include, newBurpScopeEntry(hostRegexp(domain, true)))
+	}
+	for _, domain := range exact {
+		options.Target.Scope.Include = append(options.Target.Scope.Include, newBurpScopeEntry(hostRegexp(domain, false)))
+	}
+	for _, ip := range s.AllIPs() {
+		options.Target.Scope.Include = append(options.Target.Scope.Include, newBurpScopeEntry(burpHost(ip)))
+	}
+
+	for _, exclude := range sortedScopeKeys(s.excludes) {
+		host := hostRegexp(exclude, true)
+		if _, err := netip.ParseAddr(exclude); err == nil || strings.Contains(exclude, "/") {
+			host = burpHost(exclude)
+		}
+		options.Target.Scope.Exclude = append(options.Target.Scope.Exclude, newBurpScopeEntry(host))
+	}
+
+	content, err := json.MarshalIndent(options, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportResult{
+		Files: []ExportFile{{Name: s.Name() + "-burp.json", Content: append(content, '\n')}},
+	}, nil
+}
+
+const zapParameterParser = "org.zaproxy.zap.model.StandardParameterParser"
+const zapParameterParserConfig = `{"kvps":"&","kvs":"=","struct":[]}`
+
+type zapParser struct {
+	Class  string `xml:"class"`
+	Config string `xml:"config"`
+}
+
+type zapContext struct {
+	XMLName xml.Name `xml:"configuration"`
+	Context struct {
+		Name       string    `xml:"name"`
+		Desc       string    `xml:"desc"`
+		InScope    bool      `xml:"inscope"`
+		IncRegexes []string  `xml:"incregexes"`
+		ExcRegexes []string  `xml:"excregexes"`
+		URLParser  zapParser `xml:"urlparser"`
+		PostParser zapParser `xml:"postparser"`
+	} `xml:"context"`
+}
+
+// zapURLRegexp wraps a host pattern so it matches complete http and https
+// URLs for that host, with an optional port, path, query and fragment.
+func zapURLRegexp(hostPattern string) string {
+	return "^https?://(?:[^/?#@]*@)?" + hostPattern + "(?::[0-9]+)?(?:[/?#].*)?$"
+}
+
+// zapHostPattern returns an unanchored host pattern for a domain, IP address
+// or IPv4 CIDR.
+func zapHostPattern(item string, withSubdomains bool) (string, error) {
+	if strings.Contains(item, "/") {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return "", err
+		}
+		return ipv4PrefixPattern(prefix)
+	}
+
+	addr, err := netip.ParseAddr(item)
+	if err == nil {
+		if addr.Is6() {
+			return regexp.QuoteMeta("[" + addr.String() + "]"), nil
+		}
+		return regexp.QuoteMeta(addr.String()), nil
+	}
+
+	if withSubdomains {
+		return "(?:[^./?#@:]+\\.)*" + regexp.QuoteMeta(item), nil
+	}
+	return regexp.QuoteMeta(item), nil
+}
+
+// ipv4PrefixPattern builds a pattern matching every dotted quad within prefix.
+func ipv4PrefixPattern(prefix netip.Prefix) (string, error) {
+	if !prefix.Addr().Is4() {
+		return "", fmt.Errorf("IPv6 CIDRs cannot be expressed as URL regexes: %s", prefix)
+	}
+
+	prefix = prefix.Masked()
+	octets := prefix.Addr().As4()
+	patterns := []string{}
+	for i, octet := range octets {
+		fixedBits := prefix.Bits() - i*8
+		switch {
+		case fixedBits >= 8:
+			patterns = append(patterns, strconv.Itoa(int(octet)))
+		case fixedBits <= 0:
+			patterns = append(patterns, numberRangePattern(0, 255))
+		default:
+			size := 1 << (8 - fixedBits)
+			patterns = append(patterns, numberRangePattern(int(octet), int(octet)+size-1))
+		}
+	}
+	return strings.Join(patterns, "\\."), nil
+}
+
+// numberRangePattern matches the decimal numbers low through high without
+// leading zeros. Runs sharing everything but their last digit are collapsed
+// into a character class.
+func numberRangePattern(low, high int) string {
+	if low == high {
+		return strconv.Itoa(low)
+	}
+
+	alternatives := []string{}
+	for n := low; n <= high; {
+		last := n - n%10 + 9
+		if last > high {
+			last = high
+		}
+
+		head := strconv.Itoa(n / 10)
+		if n < 10 {
+			head = ""
+		}
+		if n == last {
+			alternatives = append(alternatives, head+strconv.Itoa(n%10))
+		} else {
+			alternatives = append(alternatives, fmt.Sprintf("%s[%d-%d]", head, n%10, last%10))
+		}
+		n = last + 1
+	}
+	return "(?:" + strings.Join(alternatives, "|") + ")"
+}
+
+func (s *Scope) exportZAP() (*ExportResult, error) {
+	result := &ExportResult{}
+	context := zapContext{}
+	context.Context.Name = s.Name()
+	context.Context.Desc = s.description
+	context.Context.InScope = true
+	context.Context.URLParser = zapParser{Class: zapParameterParser, Config: zapParameterParserConfig}
+	context.Context.PostParser = zapParser{Class: zapParameterParser, Config: zapParameterParserConfig}
+
+	addRegex := func(regexes *[]string, item string, withSubdomains bool) {
+		hostPattern, err := zapHostPattern(item, withSubdomains)
+		if err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+			return
+		}
+		*regexes = append(*regexes, zapURLRegexp(hostPattern))
+	}
+
+	wildcards, exact := s.domainRules()
+	for _, domain := range wildcards {
+		addRegex(&context.Context.IncRegexes, domain, true)
+	}
+	for _, domain := range exact {
+		addRegex(&context.Context.IncRegexes, domain, false)
+	}
+	for _, ip := range s.AllIPs() {
+		addRegex(&context.Context.IncRegexes, ip, false)
+	}
+	for _, exclude := range sortedScopeKeys(s.excludes) {
+		addRegex(&context.Context.ExcRegexes, exclude, true)
+	}
+
+	content, err := xml.MarshalIndent(context, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	content = append([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>`+"\n"), content...)
+
+	result.Files = []ExportFile{{Name: s.Name() + ".context", Content: append(content, '\n')}}
+	return result, nil
+}
+
+// scannerTargets splits the scope into target and exclude lists for network
+// scanners. Targets are the aggregated included address space plus the in
+// scope domains, excludes are the excluded address space overlapping those
+// targets plus the excluded hostnames.
+func (s *Scope) scannerTargets() (targets []netip.Prefix, excludes []netip.Prefix, domains []string, excludedHostnames []string) {
+	targets = s.IncludedPrefixes()
+	excludes = utils.IntersectPrefixes(s.ExcludedPrefixes(), targets)
+
+	matcher := s.Compile()
+	for _, domain := range s.AllDomains() {
+		if matcher.ContainsDomain(domain) {
+			domains = append(domains, domain)
+		}
+	}
+
+	_, _, excludedHostnames = getCIDRsIPsHostname(s.excludes)
+	sort.Strings(excludedHostnames)
+	return
+}
+
+func prefixLines(prefixes []netip.Prefix) []byte {
+	lines := []string{}
+	for _, prefix := range prefixes {
+		lines = append(lines, utils.PrefixString(prefix))
+	}
+	return linesContent(lines)
+}
+
+func linesContent(lines []string) []byte {
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// exportScanner writes target and exclude files for nmap (-iL, --excludefile),
+// masscan (-iL, --excludefile) and nuclei (-l, -exclude-hosts). nmap only
+// scans IPv6 with -6, which it can't mix with IPv4, so IPv6 address space gets
+// its own nmap files.
+func (s *Scope) exportScanner(format string) (*ExportResult, error) {
+	targets, excludes, domains, excludedHostnames := s.scannerTargets()
+
+	result := &ExportResult{}
+	var targets6, excludes6 []netip.Prefix
+	if format == ExportFormatNmap {
+		targets, targets6 = splitPrefixes(targets)
+		excludes, excludes6 = splitPrefixes(excludes)
+	}
+	targetContent := prefixLines(targets)
+	excludeContent := prefixLines(excludes)
+
+	if format == ExportFormatMasscan {
+		// masscan only scans addresses, hostnames are not accepted at all
+		if len(domains) > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("masscan does not accept hostnames, %d in scope domains were not exported", len(domains)))
+		}
+	} else {
+		targetContent = append(targetContent, linesContent(domains)...)
+		excludeContent = append(excludeContent, linesContent(excludedHostnames)...)
+	}
+
+	result.Files = []ExportFile{
+		{Name: fmt.Sprintf("%s-%s-targets.txt", s.Name(), format), Content: targetContent},
+		{Name: fmt.Sprintf("%s-%s-exclude.txt", s.Name(), format), Content: excludeContent},
+	}
+	if len(targets6) > 0 {
+		targetsName := fmt.Sprintf("%s-%s-targets6.txt", s.Name(), format)
+		result.Files = append(result.Files,
+			ExportFile{Name: targetsName, Content: prefixLines(targets6)},
+			ExportFile{Name: fmt.Sprintf("%s-%s-exclude6.txt", s.Name(), format), Content: prefixLines(excludes6)},
+		)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("IPv6 targets are in %s, scan them separately with nmap -6", targetsName))
+	}
+	return result, nil
+}
+
+// splitPrefixes splits prefixes into IPv4 and IPv6 prefixes.
+func splitPrefixes(prefixes []netip.Prefix) (ipv4 []netip.Prefix, ipv6 []netip.Prefix) {
+	for _, prefix := range prefixes {
+		if prefix.Addr().Is4() {
+			ipv4 = append(ipv4, prefix)
+		} else {
+			ipv6 = append(ipv6, prefix)
+		}
+	}
+	return
+}
+
+func (s *Scope) exportMatcher(format string) (*ExportResult, error) {
+	matcher := s.Compile()
+	if format == ExportFormatMatcherBinary {
+		content, err := matcher.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return &ExportResult{Files: []ExportFile{{Name: s.Name() + "-matcher.bin", Content: content}}}, nil
+	}
+
+	content, err := json.Marshal(matcher)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportResult{Files: []ExportFile{{Name: s.Name() + "-matcher.json", Content: append(content, '\n')}}}, nil
+}
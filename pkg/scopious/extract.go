@@ -0,0 +1,247 @@
+package scopious
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	ExtractedKindURL    = "url"
+	ExtractedKindRange  = "range"
+	ExtractedKindCIDR   = "cidr"
+	ExtractedKindIPv4   = "ipv4"
+	ExtractedKindIPv6   = "ipv6"
+	ExtractedKindDomain = "domain"
+)
+
+// ExtractedItem is a scope item found in free form text.
+type ExtractedItem struct {
+	Item    string `json:"item"`
+	Kind    string `json:"kind"`
+	Source  string `json:"source"`
+	Line    int    `json:"line"`
+	Exclude bool   `json:"exclude"`
+}
+
+var (
+	extractURLRegexp    = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s<>"'|,;()]+`)
+	extractRangeRegexp  = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\s*-\s*(?:\d{1,3}(?:\.\d{1,3}){3}|\d{1,3})\b`)
+	extractIPv4Regexp   = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?:/\d{1,2})?\b`)
+	extractIPv6Regexp   = regexp.MustCompile(`[0-9a-fA-F]*:[0-9a-fA-F:.]*:[0-9a-fA-F.]*(?:/\d{1,3})?`)
+	extractDomainRegexp = regexp.MustCompile(`(?:\*\.)?(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z][a-zA-Z0-9-]{0,61}[a-zA-Z0-9]`)
+
+	excludeHeadingRegexp = regexp.MustCompile(`(?i)\b(?:out[- ]of[- ]scope|not in[- ]scope|exclu(?:de|ded|des|sions?))\b`)
+	includeHeadingRegexp = regexp.MustCompile(`(?i)\b(?:in[- ]scope|scope|targets?|includ(?:e|ed|es)|assets)\b`)
+)
+
+// top level domains that are more often file extensions in scope documents,
+// readme.md or backup.zip. Domains under them are only extracted from URLs.
+var fileExtensionSuffixes = map[string]bool{
+	"cs":  true,
+	"md":  true,
+	"mov": true,
+	"ps":  true,
+	"py":  true,
+	"rs":  true,
+	"sh":  true,
+	"zip": true,
+}
+
+// the most words a line can have and still be taken for a heading
+const maxHeadingWords = 6
+
+// isHeading reports whether line looks like a heading rather than prose: a
+// Markdown heading, or a short line that isn't a sentence or a table row,
+// like "Out of scope:" or "**Exclusions**".
+func isHeading(line string) bool {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return true
+	}
+	if line == "" || strings.HasPrefix(line, "|") {
+		return false
+	}
+	line = strings.TrimRight(strings.Trim(line, "*_ "), ":")
+	if strings.HasSuffix(line, ".") {
+		return false
+	}
+	return len(strings.Fields(line)) <= maxHeadingWords
+}
+
+// hasPublicSuffix reports whether domain ends in a suffix from the public
+// suffix list and has a registrable domain.
+func hasPublicSuffix(domain string) bool {
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	if !icann && !strings.Contains(suffix, ".") {
+		// not on the list at all, publicsuffix falls back to the last label
+		return false
+	}
+	_, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	return err == nil
+}
+
+// Extract scans free form text for URLs, address ranges, CIDRs, IP addresses
+// and domains. Items found under an "out of scope" or "exclusions" heading, or
+// on a line mentioning one, are marked as excludes until an "in scope" heading
+// is found. Only heading-like lines switch sections, prose mentioning scope
+// doesn't.
+func Extract(r io.Reader) ([]ExtractedItem, error) {
+	items := []ExtractedItem{}
+	seen := map[string]bool{}
+	excludeSection := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+
+		lineItems := extractLine(line)
+		if len(lineItems) == 0 {
+			if !isHeading(line) {
+				continue
+			}
+			if excludeHeadingRegexp.MatchString(line) {
+				excludeSection = true
+			} else if includeHeadingRegexp.MatchString(line) {
+				excludeSection = false
+			}
+			continue
+		}
+
+		exclude := excludeSection
+		if excludeHeadingRegexp.MatchString(line) {
+			exclude = true
+		} else if includeHeadingRegexp.MatchString(line) {
+			exclude = false
+		}
+
+		for _, item := range lineItems {
+			item.Line = lineNumber
+			item.Exclude = exclude
+
+			key := item.Item
+			if exclude {
+				key = "!" + key
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			items = append(items, item)
+		}
+	}
+	return items, scanner.Err()
+}
+
+// extractLine finds the scope items within a single line. Each pass blanks out
+// what it matched so later, looser patterns don't match inside earlier ones.
+func extractLine(line string) []ExtractedItem {
+	items := []ExtractedItem{}
+	remaining := []byte(line)
+
+	each := func(re *regexp.Regexp, action func(match string, start int) []ExtractedItem) {
+		for _, loc := range re.FindAllIndex(remaining, -1) {
+			match := string(remaining[loc[0]:loc[1]])
+			found := action(match, loc[0])
+			if len(found) == 0 {
+				continue
+			}
+			items = append(items, found...)
+			for i := loc[0]; i < loc[1]; i++ {
+				remaining[i] = ' '
+			}
+		}
+	}
+
+	each(extractURLRegexp, func(match string, start int) []ExtractedItem {
+		match = strings.TrimRight(match, ".:!?)]}")
+		item := normalizedScope(match)
+		if item == "" {
+			return nil
+		}
+		return []ExtractedItem{{Item: item, Kind: ExtractedKindURL, Source: match}}
+	})
+
+	each(extractRangeRegexp, func(match string, start int) []ExtractedItem {
+		startAddr, endAddr, err := utils.ParseRange(match)
+		if err != nil {
+			return nil
+		}
+		found := []ExtractedItem{}
+		for _, prefix := range utils.RangeToPrefixes(startAddr, endAddr) {
+			found = append(found, ExtractedItem{Item: utils.PrefixString(prefix), Kind: ExtractedKindRange, Source: match})
+		}
+		return found
+	})
+
+	each(extractIPv4Regexp, func(match string, start int) []ExtractedItem {
+		if strings.Contains(match, "/") {
+			prefix, err := netip.ParsePrefix(match)
+			if err != nil {
+				return nil
+			}
+			return []ExtractedItem{{Item: prefix.Masked().String(), Kind: ExtractedKindCIDR, Source: match}}
+		}
+		addr, err := netip.ParseAddr(match)
+		if err != nil {
+			return nil
+		}
+		return []ExtractedItem{{Item: addr.String(), Kind: ExtractedKindIPv4, Source: match}}
+	})
+
+	each(extractIPv6Regexp, func(match string, start int) []ExtractedItem {
+		match = strings.TrimRight(match, ".:")
+		if strings.Contains(match, "/") {
+			prefix, err := netip.ParsePrefix(match)
+			if err != nil || !prefix.Addr().Is6() {
+				return nil
+			}
+			return []ExtractedItem{{Item: prefix.Masked().String(), Kind: ExtractedKindCIDR, Source: match}}
+		}
+		addr, err := netip.ParseAddr(match)
+		if err != nil || !addr.Is6() || addr.IsUnspecified() {
+			return nil
+		}
+		return []ExtractedItem{{Item: addr.String(), Kind: ExtractedKindIPv6, Source: match}}
+	})
+
+	each(extractDomainRegexp, func(match string, start int) []ExtractedItem {
+		if start > 0 && (remaining[start-1] == '@' || remaining[start-1] == '.' || remaining[start-1] == '-') {
+			// email addresses and fragments of something larger
+			return nil
+		}
+		domain := strings.ToLower(strings.TrimPrefix(match, "*."))
+		if !hasPublicSuffix(domain) || fileExtensionSuffixes[domain[strings.LastIndex(domain, ".")+1:]] {
+			return nil
+		}
+		return []ExtractedItem{{Item: domain, Kind: ExtractedKindDomain, Source: match}}
+	})
+
+	return items
+}
+
+// ExtractedImport converts extracted items into includes and excludes that
+// can be applied with Scope.Import.
+func ExtractedImport(items []ExtractedItem) ImportResult {
+	result := ImportResult{
+		Includes: []string{},
+		Excludes: []string{},
+		Unmapped: []ProgramAsset{},
+	}
+	for _, item := range items {
+		if item.Exclude {
+			result.Excludes = append(result.Excludes, item.Item)
+		} else {
+			result.Includes = append(result.Includes, item.Item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package scopious
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "extract.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	items, err := Extract(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, item := range items {
+		verdict := "+"
+		if item.Exclude {
+			verdict = "-"
+		}
+		got = append(got, verdict+item.Item)
+	}
+
+	want := []string{
+		"+portal.example.com",
+		"+api.example.com",
+		"+example.com",
+		"+203.0.113.0/25",
+		"+198.51.100.10/31",
+		"+198.51.100.12/31",
+		"+2001:db8:1::/48",
+		"-legacy.example.net",
+		"+192.0.2.15",
+		"-203.0.113.64/26",
+		"-status.example.com",
+		"-2001:db8:1::53",
+		"-example.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func Test_hasPublicSuffix(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{domain: "example.com", want: true},
+		{domain: "www.example.co.uk", want: true},
+		{domain: "user.github.io", want: true},
+		{domain: "co.uk", want: false},
+		{domain: "com", want: false},
+		{domain: "readme.txt", want: false},
+		{domain: "host.internal.corp", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := hasPublicSuffix(tt.domain); got != tt.want {
+				t.Errorf("hasPublicSuffix(%s) = %v, want %v", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isHeading(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{line: "## Out of Scope", want: true},
+		{line: "Exclusions:", want: true},
+		{line: "**In scope**", want: true},
+		{line: "Anything not listed is out of scope.", want: false},
+		{line: "The following assets are excluded from testing, please check with us first", want: false},
+		{line: "| In scope | Notes |", want: false},
+		{line: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := isHeading(tt.line); got != tt.want {
+				t.Errorf("isHeading(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
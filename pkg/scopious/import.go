@@ -0,0 +1,365 @@
+package scopious
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+const (
+	ImportFormatHackerOne = "hackerone"
+	ImportFormatBugcrowd  = "bugcrowd"
+	ImportFormatIntigriti = "intigriti"
+)
+
+// ProgramAsset is a single asset from a bug bounty program scope export.
+type ProgramAsset struct {
+	Identifier string `json:"identifier"`
+	Type       string `json:"type"`
+	InScope    bool   `json:"in_scope"`
+}
+
+// ImportResult holds the scope items mapped from program assets, along with
+// the assets that could not be expressed as scope items.
+type ImportResult struct {
+	Includes []string          `json:"includes"`
+	Excludes []string          `json:"excludes"`
+	Unmapped []ProgramAsset    `json:"unmapped"`
+	Notes    map[string]string `json:"notes,omitempty"`
+	// Warnings lists out of scope targets that were skipped, like URLs with a
+	// path, which can't be excluded without excluding the whole host.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// asset types that can never be mapped to hosts, addresses or networks
+var unmappableAssetTypes = map[string]bool{
+	"android":                  true,
+	"apple_store_app_id":       true,
+	"google_play_app_id":       true,
+	"other_apk":                true,
+	"other_ipa":                true,
+	"testflight":               true,
+	"windows_app_store_app_id": true,
+	"ios":                      true,
+	"mobile":                   true,
+	"source_code":              true,
+	"downloadable_executables": true,
+	"executable":               true,
+	"hardware":                 true,
+	"device":                   true,
+	"smart_contract":           true,
+	"ai_model":                 true,
+	"other":                    true,
+}
+
+type hackerOneScope struct {
+	AssetType             string `json:"asset_type"`
+	AssetIdentifier       string `json:"asset_identifier"`
+	EligibleForSubmission *bool  `json:"eligible_for_submission"`
+}
+
+type hackerOneRecord struct {
+	Attributes hackerOneScope `json:"attributes"`
+}
+
+type hackerOneExport struct {
+	Data          []hackerOneRecord `json:"data"`
+	Relationships struct {
+		StructuredScopes struct {
+			Data []hackerOneRecord `json:"data"`
+		} `json:"structured_scopes"`
+	} `json:"relationships"`
+	Targets struct {
+		InScope    []hackerOneScope `json:"in_scope"`
+		OutOfScope []hackerOneScope `json:"out_of_scope"`
+	} `json:"targets"`
+}
+
+type bugcrowdTarget struct {
+	Name     string `json:"name"`
+	Target   string `json:"target"`
+	URI      string `json:"uri"`
+	Category string `json:"category"`
+	Type     string `json:"type"`
+}
+
+type bugcrowdExport struct {
+	Groups []struct {
+		InScope bool             `json:"in_scope"`
+		Targets []bugcrowdTarget `json:"targets"`
+	} `json:"groups"`
+	Targets struct {
+		InScope    []bugcrowdTarget `json:"in_scope"`
+		OutOfScope []bugcrowdTarget `json:"out_of_scope"`
+	} `json:"targets"`
+}
+
+type intigritiDomain struct {
+	Endpoint string `json:"endpoint"`
+	Type     any    `json:"type"`
+	Tier     any    `json:"tier"`
+}
+
+type intigritiExport struct {
+	Domains json.RawMessage `json:"domains"`
+	Targets struct {
+		InScope    []intigritiDomain `json:"in_scope"`
+		OutOfScope []intigritiDomain `json:"out_of_scope"`
+	} `json:"targets"`
+}
+
+// ParseProgramScope reads a HackerOne, Bugcrowd or Intigriti program scope
+// export. Both the platform API responses and the flattened
+// in_scope/out_of_scope layout used by bounty-targets-data are understood.
+func ParseProgramScope(format string, r io.Reader) ([]ProgramAsset, error) {
+	switch format {
+	case ImportFormatHackerOne:
+		return parseHackerOne(r)
+	case ImportFormatBugcrowd:
+		return parseBugcrowd(r)
+	case ImportFormatIntigriti:
+		return parseIntigriti(r)
+	}
+	return nil, fmt.Errorf("unknown import format: %s", format)
+}
+
+func parseHackerOne(r io.Reader) ([]ProgramAsset, error) {
+	export := hackerOneExport{}
+	err := json.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := []ProgramAsset{}
+	records := append(export.Data, export.Relationships.StructuredScopes.Data...)
+	for _, record := range records {
+		eligible := record.Attributes.EligibleForSubmission == nil || *record.Attributes.EligibleForSubmission
+		assets = append(assets, ProgramAsset{
+			Identifier: record.Attributes.AssetIdentifier,
+			Type:       record.Attributes.AssetType,
+			InScope:    eligible,
+		})
+	}
+
+	for _, target := range export.Targets.InScope {
+		eligible := target.EligibleForSubmission == nil || *target.EligibleForSubmission
+		assets = append(assets, ProgramAsset{Identifier: target.AssetIdentifier, Type: target.AssetType, InScope: eligible})
+	}
+	for _, target := range export.Targets.OutOfScope {
+		assets = append(assets, ProgramAsset{Identifier: target.AssetIdentifier, Type: target.AssetType})
+	}
+	return assets, nil
+}
+
+func (t bugcrowdTarget) asset(inScope bool) ProgramAsset {
+	identifier := t.URI
+	if identifier == "" {
+		identifier = t.Target
+	}
+	if identifier == "" {
+		identifier = t.Name
+	}
+
+	assetType := t.Category
+	if assetType == "" {
+		assetType = t.Type
+	}
+	return ProgramAsset{Identifier: identifier, Type: assetType, InScope: inScope}
+}
+
+func parseBugcrowd(r io.Reader) ([]ProgramAsset, error) {
+	export := bugcrowdExport{}
+	err := json.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	assets := []ProgramAsset{}
+	for _, group := range export.Groups {
+		for _, target := range group.Targets {
+			assets = append(assets, target.asset(group.InScope))
+		}
+	}
+	for _, target := range export.Targets.InScope {
+		assets = append(assets, target.asset(true))
+	}
+	for _, target := range export.Targets.OutOfScope {
+		assets = append(assets, target.asset(false))
+	}
+	return assets, nil
+}
+
+// intigritiString handles fields that are either plain strings or {id, value} objects.
+func intigritiString(field any) string {
+	switch v := field.(type) {
+	case string:
+		return v
+	case map[string]any:
+		value, _ := v["value"].(string)
+		return value
+	}
+	return ""
+}
+
+func (d intigritiDomain) asset(inScope bool) ProgramAsset {
+	if strings.EqualFold(intigritiString(d.Tier), "out of scope") {
+		inScope = false
+	}
+	return ProgramAsset{Identifier: d.Endpoint, Type: intigritiString(d.Type), InScope: inScope}
+}
+
+func parseIntigriti(r io.Reader) ([]ProgramAsset, error) {
+	export := intigritiExport{}
+	err := json.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []intigritiDomain{}
+	if len(export.Domains) > 0 {
+		// domains is either a plain list or a paged {"content": [...]} object
+		err = json.Unmarshal(export.Domains, &domains)
+		if err != nil {
+			paged := struct {
+				Content []intigritiDomain `json:"content"`
+			}{}
+			err = json.Unmarshal(export.Domains, &paged)
+			if err != nil {
+				return nil, err
+			}
+			domains = paged.Content
+		}
+	}
+
+	assets := []ProgramAsset{}
+	for _, domain := range domains {
+		assets = append(assets, domain.asset(true))
+	}
+	for _, domain := range export.Targets.InScope {
+		assets = append(assets, domain.asset(true))
+	}
+	for _, domain := range export.Targets.OutOfScope {
+		assets = append(assets, domain.asset(false))
+	}
+	return assets, nil
+}
+
+// MapProgramAssets converts program assets into scope items. In scope assets
+// become includes and out of scope assets become excludes. Wildcards like
+// *.example.com map to their base domain, address ranges map to CIDRs.
+func MapProgramAssets(assets []ProgramAsset) ImportResult {
+	result := ImportResult{
+		Includes: []string{},
+		Excludes: []string{},
+		Unmapped: []ProgramAsset{},
+	}
+
+	for _, asset := range assets {
+		assetType := strings.ToLower(strings.TrimSpace(asset.Type))
+		if unmappableAssetTypes[assetType] {
+			result.Unmapped = append(result.Unmapped, asset)
+			continue
+		}
+
+		mapping := mapAssetIdentifier(asset.Identifier, !asset.InScope)
+		for _, target := range mapping.skipped {
+			result.Warnings = append(result.Warnings, skippedPathWarning(target))
+		}
+		if len(mapping.items) == 0 {
+			if len(mapping.skipped) == 0 {
+				result.Unmapped = append(result.Unmapped, asset)
+			}
+			continue
+		}
+		for _, target := range mapping.invalid {
+			result.Warnings = append(result.Warnings, invalidTargetWarning(target, asset.Identifier))
+		}
+
+		if asset.InScope {
+			result.Includes = append(result.Includes, mapping.items...)
+		} else {
+			result.Excludes = append(result.Excludes, mapping.items...)
+		}
+	}
+	return result
+}
+
+// Import adds the excludes and then the includes of an ImportResult to the
+// scope, along with any notes.
+func (s *Scope) Import(result ImportResult) {
+	s.AddExclude(result.Excludes...)
+	s.Add(result.Includes...)
+	for item, note := range result.Notes {
+		s.SetNote(item, note)
+	}
+}
+
+// assetMapping is what the targets of an asset identifier map to.
+type assetMapping struct {
+	items []string
+	// skipped are excluded targets limited to a path
+	skipped []string
+	// invalid are targets that can't be mapped to a scope item
+	invalid []string
+}
+
+// mapAssetIdentifier maps the targets in an asset identifier to scope items.
+// Each target is mapped on its own, so one bad target doesn't lose the rest.
+// Scope is host based, so when exclude is set, targets limited to a path like
+// https://example.com/admin are skipped rather than excluding the whole host.
+func mapAssetIdentifier(identifier string, exclude bool) assetMapping {
+	mapping := assetMapping{items: []string{}}
+	fields := strings.FieldsFunc(identifier, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	for _, field := range fields {
+		target := field
+		path := ""
+		if strings.Contains(field, "://") {
+			_, field, _ = strings.Cut(field, "://")
+			field, path, _ = strings.Cut(field, "/")
+		} else if _, _, err := net.ParseCIDR(field); err != nil {
+			// drop any path, example.com/api
+			field, path, _ = strings.Cut(field, "/")
+		}
+
+		if exclude && strings.Trim(path, "/") != "" {
+			mapping.skipped = append(mapping.skipped, target)
+			continue
+		}
+
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "*"), ".")
+		if strings.Contains(field, "*") {
+			mapping.invalid = append(mapping.invalid, target)
+			continue
+		}
+
+		if start, end, err := utils.ParseRange(field); err == nil {
+			for _, prefix := range utils.RangeToPrefixes(start, end) {
+				mapping.items = append(mapping.items, prefix.String())
+			}
+			continue
+		}
+
+		item := normalizedScope(field)
+		if item == "" {
+			mapping.invalid = append(mapping.invalid, target)
+			continue
+		}
+		mapping.items = append(mapping.items, item)
+	}
+	return mapping
+}
+
+func skippedPathWarning(target string) string {
+	return fmt.Sprintf("skipped out of scope %s, excluding it would exclude the whole host", target)
+}
+
+func invalidTargetWarning(target string, identifier string) string {
+	return fmt.Sprintf("skipped %s in %s, it can't be mapped to a scope item", target, identifier)
+}
@@ -0,0 +1,232 @@
+package scopious
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProgramScope(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       string
+		file         string
+		wantIncludes []string
+		wantExcludes []string
+		wantUnmapped []string
+	}{
+		{
+			name:         "hackerone structured scopes",
+			format:       ImportFormatHackerOne,
+			file:         "hackerone.json",
+			wantIncludes: []string{"example.com", "api.example.net", "203.0.113.0/25"},
+			wantExcludes: []string{"corp.example.com"},
+			wantUnmapped: []string{"com.example.android", "https://github.com/example/app"},
+		},
+		{
+			name:         "bugcrowd target groups",
+			format:       ImportFormatBugcrowd,
+			file:         "bugcrowd.json",
+			wantIncludes: []string{"example.org", "api.example.io", "192.0.2.10/31", "192.0.2.12/31"},
+			wantExcludes: []string{"status.example.org"},
+			wantUnmapped: []string{"https://apps.apple.com/app/id000000000", "*.example-*.org"},
+		},
+		{
+			name:         "intigriti domains",
+			format:       ImportFormatIntigriti,
+			file:         "intigriti.json",
+			wantIncludes: []string{"example.eu", "shop.example.be", "198.51.100.0/24"},
+			wantExcludes: []string{"legacy.example.eu"},
+			wantUnmapped: []string{"be.example.app"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", "import", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			assets, err := ParseProgramScope(tt.format, file)
+			if err != nil {
+				t.Fatalf("ParseProgramScope() error = %v", err)
+			}
+
+			result := MapProgramAssets(assets)
+			if !reflect.DeepEqual(result.Includes, tt.wantIncludes) {
+				t.Errorf("Includes = %v, want %v", result.Includes, tt.wantIncludes)
+			}
+			if !reflect.DeepEqual(result.Excludes, tt.wantExcludes) {
+				t.Errorf("Excludes = %v, want %v", result.Excludes, tt.wantExcludes)
+			}
+
+			unmapped := []string{}
+			for _, asset := range result.Unmapped {
+				unmapped = append(unmapped, asset.Identifier)
+			}
+			if !reflect.DeepEqual(unmapped, tt.wantUnmapped) {
+				t.Errorf("Unmapped = %v, want %v", unmapped, tt.wantUnmapped)
+			}
+		})
+	}
+}
+
+func TestScope_Import(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.Import(ImportResult{
+		Includes: []string{"example.com", "admin.example.com", "203.0.113.0/25"},
+		Excludes: []string{"admin.example.com"},
+	})
+
+	wantDomains := map[string]bool{"example.com": true}
+	if !reflect.DeepEqual(s.domains, wantDomains) {
+		t.Errorf("Domains = %v, want %v", s.domains, wantDomains)
+	}
+	if s.IsInScope("www.admin.example.com") {
+		t.Errorf("IsInScope(www.admin.example.com) = true, want false")
+	}
+	if !s.IsInScope("203.0.113.7") {
+		t.Errorf("IsInScope(203.0.113.7) = false, want true")
+	}
+}
+
+func TestParseTabularScope(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "import", "scope.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	columns, err := ParseColumnMapping("asset=Host,include=InScope,scope=Environment")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseTabularScope(file, columns, DefaultScope)
+	if err != nil {
+		t.Fatalf("ParseTabularScope() error = %v", err)
+	}
+
+	invalidRows := []int{}
+	for _, invalid := range result.Invalid {
+		invalidRows = append(invalidRows, invalid.Row)
+	}
+	if !reflect.DeepEqual(invalidRows, []int{7, 8, 9}) {
+		t.Errorf("invalid rows = %v, want [7 8 9]", invalidRows)
+	}
+
+	if !reflect.DeepEqual(result.ScopeNames(), []string{"default", "production", "staging"}) {
+		t.Errorf("ScopeNames() = %v", result.ScopeNames())
+	}
+
+	byScope := result.ByScope()
+	production := byScope["production"]
+	if !reflect.DeepEqual(production.Includes, []string{"portal.example.com", "203.0.113.0/25"}) {
+		t.Errorf("production Includes = %v", production.Includes)
+	}
+	if !reflect.DeepEqual(production.Excludes, []string{"status.example.com"}) {
+		t.Errorf("production Excludes = %v", production.Excludes)
+	}
+	if production.Notes["portal.example.com"] != "Customer portal, behind WAF" {
+		t.Errorf("production Notes = %v", production.Notes)
+	}
+	if !reflect.DeepEqual(byScope["staging"].Includes, []string{"198.51.100.10/31", "198.51.100.12/31"}) {
+		t.Errorf("staging Includes = %v", byScope["staging"].Includes)
+	}
+	if !reflect.DeepEqual(byScope["default"].Includes, []string{"staging.example.com"}) {
+		t.Errorf("default Includes = %v", byScope["default"].Includes)
+	}
+}
+
+func TestParseColumnMapping(t *testing.T) {
+	columns, err := ParseColumnMapping("asset=Host, note=Comments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultColumnMapping
+	want.Asset = "Host"
+	want.Note = "Comments"
+	if columns != want {
+		t.Errorf("ParseColumnMapping() = %v, want %v", columns, want)
+	}
+
+	for _, mapping := range []string{"host=Host", "asset", "asset="} {
+		if _, err := ParseColumnMapping(mapping); err == nil {
+			t.Errorf("ParseColumnMapping(%q) error = nil, want error", mapping)
+		}
+	}
+}
+
+func TestScope_Notes(t *testing.T) {
+	dir := t.TempDir()
+	s := NewScopeFromPath(dir)
+	s.Import(ImportResult{
+		Includes: []string{"example.com"},
+		Notes:    map[string]string{"https://example.com/": "primary\tsite"},
+	})
+	s.Save()
+
+	loaded := NewScopeFromPath(dir)
+	loaded.Load()
+	if !reflect.DeepEqual(loaded.notes, map[string]string{"example.com": "primary site"}) {
+		t.Errorf("Notes = %v", loaded.notes)
+	}
+
+	// cleared values aren't read back from files left behind
+	loaded.SetDescription("External")
+	loaded.SetWindows([]string{"2026-11-01 09:00-17:00 UTC"})
+	loaded.Save()
+	loaded.SetNote("example.com", "")
+	loaded.SetDescription("")
+	loaded.SetWindows(nil)
+	loaded.Save()
+
+	loaded = NewScopeFromPath(dir)
+	loaded.Load()
+	if len(loaded.notes) != 0 || loaded.Description() != "" || len(loaded.Windows()) != 0 {
+		t.Errorf("Notes = %v, Description = %q, Windows = %v after clearing", loaded.notes, loaded.Description(), loaded.Windows())
+	}
+}
+
+func TestMapProgramAssets_ExcludedPaths(t *testing.T) {
+	result := MapProgramAssets([]ProgramAsset{
+		{Identifier: "*.example.com", Type: "wildcard", InScope: true},
+		{Identifier: "https://example.com/admin", Type: "url", InScope: false},
+		{Identifier: "https://status.example.com/", Type: "url", InScope: false},
+		{Identifier: "dev.example.com/api, legacy.example.com", Type: "url", InScope: false},
+	})
+
+	if !reflect.DeepEqual(result.Excludes, []string{"status.example.com", "legacy.example.com"}) {
+		t.Errorf("Excludes = %v", result.Excludes)
+	}
+	if len(result.Unmapped) != 0 {
+		t.Errorf("Unmapped = %v, want none", result.Unmapped)
+	}
+	want := []string{
+		"skipped out of scope https://example.com/admin, excluding it would exclude the whole host",
+		"skipped out of scope dev.example.com/api, excluding it would exclude the whole host",
+	}
+	if !reflect.DeepEqual(result.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", result.Warnings, want)
+	}
+}
+
+func TestMapProgramAssets_MixedIdentifier(t *testing.T) {
+	result := MapProgramAssets([]ProgramAsset{
+		{Identifier: "a.example.com,foo.*.example.com, 203.0.113.5", Type: "url", InScope: true},
+		{Identifier: "foo.*.example.org", Type: "url", InScope: true},
+	})
+
+	if !reflect.DeepEqual(result.Includes, []string{"a.example.com", "203.0.113.5"}) {
+		t.Errorf("Includes = %v", result.Includes)
+	}
+	if len(result.Unmapped) != 1 || result.Unmapped[0].Identifier != "foo.*.example.org" {
+		t.Errorf("Unmapped = %v", result.Unmapped)
+	}
+	want := []string{"skipped foo.*.example.com in a.example.com,foo.*.example.com, 203.0.113.5, it can't be mapped to a scope item"}
+	if !reflect.DeepEqual(result.Warnings, want) {
+		t.Errorf("Warnings = %v, want %v", result.Warnings, want)
+	}
+}
@@ -0,0 +1,292 @@
+package scopious
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/state"
+)
+
+const (
+	InputFormatLine        = "line"
+	InputFormatNmapXML     = "nmap-xml"
+	InputFormatMasscanJSON = "masscan-json"
+	InputFormatJSONL       = "jsonl"
+	InputFormatNessus      = "nessus"
+)
+
+// DefaultInputField is the JSON lines field checked when none is supplied.
+// httpx, subfinder and dnsx all report the target in this field.
+const DefaultInputField = "host"
+
+// InputRecord is a single record from tool output. Raw is the original record
+// text, Items are the hosts, addresses or URLs found in it.
+type InputRecord struct {
+	Raw   string
+	Items []string
+}
+
+// InScope reports whether a record should be kept: at least one of its items
+// is in scope and none of them are excluded. Excludes win, a host at an
+// excluded address is dropped even when its PTR name is in scope.
+func (r InputRecord) InScope(explain func(item string) Explanation) bool {
+	inScope := false
+	for _, item := range r.Items {
+		explanation := explain(item)
+		if explanation.Excluded() {
+			return false
+		}
+		inScope = inScope || explanation.InScope
+	}
+	return inScope
+}
+
+// ReadInput reads records in the given format and calls action for each one.
+// field selects the JSON lines value to check, nested fields are separated by
+// a dot, e.g. a.b.
+func ReadInput(r io.Reader, format string, field string, action func(record InputRecord)) error {
+	switch format {
+	case "", InputFormatLine:
+		return readLines(r, func(line string) {
+			action(InputRecord{Raw: line, Items: []string{line}})
+		})
+	case InputFormatJSONL:
+		return readLines(r, func(line string) {
+			record, ok := parseJSONLRecord(line, field)
+			if ok {
+				action(record)
+			}
+		})
+	case InputFormatMasscanJSON:
+		return readLines(r, func(line string) {
+			record, ok := parseMasscanRecord(line)
+			if ok {
+				action(record)
+			}
+		})
+	case InputFormatNmapXML, InputFormatNessus:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return readXMLRecords(data, format, func(record InputRecord, start, end int64) {
+			action(record)
+		})
+	}
+	return fmt.Errorf("unknown input format: %s", format)
+}
+
+// FilterInput copies the records for which keep returns true from r to w. XML
+// documents keep everything surrounding the records so the output can still be
+// loaded by tools expecting the original format.
+func FilterInput(r io.Reader, w io.Writer, format string, field string, keep func(record InputRecord) bool) error {
+	if format != InputFormatNmapXML && format != InputFormatNessus {
+		var writeErr error
+		err := ReadInput(r, format, field, func(record InputRecord) {
+			if writeErr == nil && keep(record) {
+				_, writeErr = fmt.Fprintln(w, record.Raw)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return writeErr
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	first, last := int64(-1), int64(0)
+	kept := &bytes.Buffer{}
+	err = readXMLRecords(data, format, func(record InputRecord, start, end int64) {
+		if first < 0 {
+			first = start
+		}
+		last = end
+		if keep(record) {
+			kept.WriteString(record.Raw)
+			kept.WriteString("\n")
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if first < 0 {
+		// no records, nothing to filter
+		_, err = w.Write(data)
+		return err
+	}
+
+	_, err = w.Write(data[:first])
+	if err == nil {
+		_, err = w.Write(kept.Bytes())
+	}
+	if err == nil {
+		_, err = w.Write(bytes.TrimLeft(data[last:], "\r\n"))
+	}
+	return err
+}
+
+func readLines(r io.Reader, action func(line string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		action(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+func parseJSONLRecord(line string, field string) (InputRecord, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return InputRecord{}, false
+	}
+
+	var value any
+	err := json.Unmarshal([]byte(line), &value)
+	if err != nil {
+		if state.Debug {
+			log.Println("error parsing json line", err)
+		}
+		return InputRecord{}, false
+	}
+
+	if field == "" {
+		field = DefaultInputField
+	}
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return InputRecord{}, false
+		}
+		value = object[key]
+	}
+
+	record := InputRecord{Raw: line}
+	switch v := value.(type) {
+	case string:
+		record.Items = append(record.Items, v)
+	case []any:
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				record.Items = append(record.Items, str)
+			}
+		}
+	}
+	return record, len(record.Items) > 0
+}
+
+// parseMasscanRecord handles masscan -oJ output. Each record is on its own
+// line inside a JSON array, older masscan versions leave a trailing comma.
+func parseMasscanRecord(line string) (InputRecord, bool) {
+	line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+	if !strings.HasPrefix(line, "{") {
+		return InputRecord{}, false
+	}
+
+	result := struct {
+		IP string `json:"ip"`
+	}{}
+	err := json.Unmarshal([]byte(line), &result)
+	if err != nil || result.IP == "" {
+		if err != nil && state.Debug {
+			log.Println("error parsing masscan record", err)
+		}
+		return InputRecord{}, false
+	}
+	return InputRecord{Raw: line, Items: []string{result.IP}}, true
+}
+
+type nmapHost struct {
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"hostnames>hostname"`
+}
+
+func (h nmapHost) items() []string {
+	items := []string{}
+	for _, address := range h.Addresses {
+		if address.AddrType != "mac" {
+			items = append(items, address.Addr)
+		}
+	}
+	for _, hostname := range h.Hostnames {
+		items = append(items, hostname.Name)
+	}
+	return items
+}
+
+type nessusHost struct {
+	Name string `xml:"name,attr"`
+	Tags []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"HostProperties>tag"`
+}
+
+func (h nessusHost) items() []string {
+	items := []string{h.Name}
+	for _, tag := range h.Tags {
+		if tag.Name == "host-ip" || tag.Name == "host-fqdn" {
+			items = append(items, tag.Value)
+		}
+	}
+	return items
+}
+
+// readXMLRecords finds every host element, nmap <host> or Nessus <ReportHost>,
+// and reports its items along with its byte offsets within data.
+func readXMLRecords(data []byte, format string, action func(record InputRecord, start, end int64)) error {
+	element := "host"
+	if format == InputFormatNessus {
+		element = "ReportHost"
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		startElement, ok := token.(xml.StartElement)
+		if !ok || startElement.Name.Local != element {
+			continue
+		}
+
+		var items []string
+		if format == InputFormatNessus {
+			host := nessusHost{}
+			err = decoder.DecodeElement(&host, &startElement)
+			items = host.items()
+		} else {
+			host := nmapHost{}
+			err = decoder.DecodeElement(&host, &startElement)
+			items = host.items()
+		}
+		if err != nil {
+			return err
+		}
+
+		end := decoder.InputOffset()
+		action(InputRecord{Raw: string(data[start:end]), Items: items}, start, end)
+	}
+}
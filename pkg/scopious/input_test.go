@@ -0,0 +1,213 @@
+package scopious
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func inputTestScope() *Scope {
+	return &Scope{
+		ipv4: map[string]bool{
+			"10.42.0.0/30": true,
+			"10.42.2.42":   true,
+		},
+		domains: map[string]bool{
+			"inscope.tld": true,
+		},
+		ipv6: map[string]bool{},
+		excludes: map[string]bool{
+			"10.42.0.0/31":           true,
+			"notinscope.inscope.tld": true,
+		},
+	}
+}
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		field  string
+		file   string
+		want   [][]string
+	}{
+		{
+			name:   "nmap xml",
+			format: InputFormatNmapXML,
+			file:   "nmap.xml",
+			want:   [][]string{{"10.42.0.2", "console.inscope.tld"}, {"203.0.113.9"}},
+		},
+		{
+			name:   "masscan json",
+			format: InputFormatMasscanJSON,
+			file:   "masscan.json",
+			want:   [][]string{{"10.42.0.3"}, {"198.51.100.20"}},
+		},
+		{
+			name:   "jsonl default field",
+			format: InputFormatJSONL,
+			file:   "httpx.jsonl",
+			want:   [][]string{{"10.42.2.42"}, {"198.51.100.7"}, {"10.42.0.1"}},
+		},
+		{
+			name:   "jsonl url field",
+			format: InputFormatJSONL,
+			field:  "url",
+			file:   "httpx.jsonl",
+			want:   [][]string{{"https://api.inscope.tld"}, {"https://cdn.thirdparty.tld"}, {"https://api.notinscope.inscope.tld"}},
+		},
+		{
+			name:   "jsonl list field",
+			format: InputFormatJSONL,
+			field:  "a",
+			file:   "httpx.jsonl",
+			want:   [][]string{{"10.42.2.42"}, {"198.51.100.7"}, {"10.42.0.1"}},
+		},
+		{
+			name:   "nessus",
+			format: InputFormatNessus,
+			file:   "scan.nessus",
+			want:   [][]string{{"10.42.2.42", "10.42.2.42", "www.inscope.tld"}, {"192.0.2.200", "192.0.2.200"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", "input", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			got := [][]string{}
+			err = ReadInput(file, tt.format, tt.field, func(record InputRecord) {
+				got = append(got, record.Items)
+			})
+			if err != nil {
+				t.Fatalf("ReadInput() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadInput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		field     string
+		file      string
+		wantItems [][]string
+		contains  []string
+	}{
+		{
+			name:      "nmap xml keeps document",
+			format:    InputFormatNmapXML,
+			file:      "nmap.xml",
+			wantItems: [][]string{{"10.42.0.2", "console.inscope.tld"}},
+			contains:  []string{"<nmaprun", "<scaninfo", `portid="443"`, "<runstats>", "</nmaprun>"},
+		},
+		{
+			name:      "masscan json",
+			format:    InputFormatMasscanJSON,
+			file:      "masscan.json",
+			wantItems: [][]string{{"10.42.0.3"}},
+			contains:  []string{`"port": 80`},
+		},
+		{
+			name:      "httpx jsonl by url",
+			format:    InputFormatJSONL,
+			field:     "url",
+			file:      "httpx.jsonl",
+			wantItems: [][]string{{"https://api.inscope.tld"}},
+			contains:  []string{`"status_code":200`},
+		},
+		{
+			name:      "nessus keeps document",
+			format:    InputFormatNessus,
+			file:      "scan.nessus",
+			wantItems: [][]string{{"10.42.2.42", "10.42.2.42", "www.inscope.tld"}},
+			contains:  []string{"<Report name", "pluginID=\"10107\"", "</NessusClientData_v2>"},
+		},
+	}
+
+	s := inputTestScope()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", "input", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			out := &bytes.Buffer{}
+			err = FilterInput(file, out, tt.format, tt.field, func(record InputRecord) bool {
+				return record.InScope(s.Explain)
+			})
+			if err != nil {
+				t.Fatalf("FilterInput() error = %v", err)
+			}
+
+			for _, want := range tt.contains {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("FilterInput() output missing %s:\n%s", want, out.String())
+				}
+			}
+
+			got := [][]string{}
+			err = ReadInput(bytes.NewReader(out.Bytes()), tt.format, tt.field, func(record InputRecord) {
+				got = append(got, record.Items)
+			})
+			if err != nil {
+				t.Fatalf("re-reading filtered output failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantItems) {
+				t.Errorf("filtered records = %v, want %v", got, tt.wantItems)
+			}
+		})
+	}
+}
+
+func TestInputRecord_InScope(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.Add("203.0.113.0/24", "example.com")
+	s.AddExclude("203.0.113.128/25", "admin.example.com")
+	matcher := s.Compile()
+
+	tests := []struct {
+		items []string
+		want  bool
+	}{
+		{items: []string{"203.0.113.10", "www.example.com"}, want: true},
+		{items: []string{"198.51.100.1", "www.example.com"}, want: true},
+		// excludes win over an in scope PTR name
+		{items: []string{"203.0.113.200", "www.example.com"}, want: false},
+		{items: []string{"203.0.113.10", "admin.example.com"}, want: false},
+		{items: []string{"198.51.100.1", "other.example.net"}, want: false},
+	}
+	for _, tt := range tests {
+		got := InputRecord{Items: tt.items}.InScope(matcher.Explain)
+		if got != tt.want {
+			t.Errorf("InScope(%v) = %v, want %v", tt.items, got, tt.want)
+		}
+	}
+
+	xml := `<nmaprun>
+<host><address addr="203.0.113.200" addrtype="ipv4"/><hostnames><hostname name="www.example.com" type="PTR"/></hostnames></host>
+<host><address addr="203.0.113.10" addrtype="ipv4"/><hostnames><hostname name="www.example.com" type="PTR"/></hostnames></host>
+</nmaprun>`
+	out := &bytes.Buffer{}
+	err := FilterInput(strings.NewReader(xml), out, InputFormatNmapXML, "", func(record InputRecord) bool {
+		return record.InScope(matcher.Explain)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "203.0.113.200") || !strings.Contains(out.String(), "203.0.113.10") {
+		t.Errorf("FilterInput() =\n%s", out.String())
+	}
+}
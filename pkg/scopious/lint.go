@@ -0,0 +1,399 @@
+package scopious
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+const (
+	LintCheckInvalidItem       = "invalid-item"
+	LintCheckInvalidSuffix     = "invalid-public-suffix"
+	LintCheckNonCanonicalCIDR  = "non-canonical-cidr"
+	LintCheckRedundantIP       = "redundant-ip"
+	LintCheckRedundantDomain   = "redundant-domain"
+	LintCheckExcludedInclude   = "fully-excluded"
+	LintCheckUnusedExclude     = "unused-exclude"
+	LintCheckNonPublicExternal = "non-public-in-external-scope"
+	LintCheckProviderRange     = "provider-range"
+)
+
+// LintFinding is a problem found in a scope file.
+type LintFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Item     string `json:"item"`
+	Message  string `json:"message"`
+	// Related is the item responsible for the finding, like the CIDR that
+	// already covers a redundant IP address.
+	Related string `json:"related,omitempty"`
+	// Fixable findings can be fixed by Scope.Fix without changing what is
+	// in scope.
+	Fixable bool `json:"fixable"`
+}
+
+// LintOptions enables the optional lint checks.
+type LintOptions struct {
+	// External reports address space that isn't publicly routable.
+	External bool
+	// Providers reports addresses belonging to cloud and CDN providers.
+	Providers *ProviderRanges
+}
+
+// Lint checks the scope for invalid, redundant and contradictory items.
+func (s *Scope) Lint(options LintOptions) []LintFinding {
+	findings := []LintFinding{}
+	findings = append(findings, s.lintInvalid()...)
+	findings = append(findings, s.lintRedundantIPs(scopeFileIPv4, s.ipv4)...)
+	findings = append(findings, s.lintRedundantIPs(scopeFileIPv6, s.ipv6)...)
+	findings = append(findings, s.lintRedundantDomains()...)
+	findings = append(findings, s.lintExcludedIncludes()...)
+	findings = append(findings, s.lintUnusedExcludes()...)
+	if options.External {
+		findings = append(findings, s.lintNonPublic()...)
+	}
+	if options.Providers != nil {
+		findings = append(findings, s.lintProviders(options.Providers)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Item < findings[j].Item
+	})
+	return findings
+}
+
+// Fix removes the items behind fixable findings and rewrites non canonical
+// CIDRs, returning how many findings were fixed. The scope is not saved.
+func (s *Scope) Fix(findings []LintFinding) int {
+	fixed := 0
+	// CIDRs are rewritten first, other findings refer to the canonical form
+	for _, finding := range findings {
+		if finding.Check != LintCheckNonCanonicalCIDR {
+			continue
+		}
+		scopeMap := s.scopeFileMap(finding.File)
+		if scopeMap[finding.Item] {
+			delete(scopeMap, finding.Item)
+			scopeMap[finding.Related] = true
+			fixed++
+		}
+	}
+
+	for _, finding := range findings {
+		if !finding.Fixable || finding.Check == LintCheckNonCanonicalCIDR {
+			continue
+		}
+		scopeMap := s.scopeFileMap(finding.File)
+		if scopeMap[finding.Item] {
+			delete(scopeMap, finding.Item)
+			delete(s.notes, finding.Item)
+			fixed++
+		}
+	}
+
+	s.rootDomainMap = map[string]bool{}
+	s.rootDomainSorted = []string{}
+	s.populateExcludes()
+	return fixed
+}
+
+func (s *Scope) scopeFileMap(file string) map[string]bool {
+	switch file {
+	case scopeFileIPv4:
+		return s.ipv4
+	case scopeFileIPv6:
+		return s.ipv6
+	case scopeFileDomains:
+		return s.domains
+	case scopeFileExclude:
+		return s.excludes
+	}
+	return nil
+}
+
+func (s *Scope) lintInvalid() []LintFinding {
+	findings := []LintFinding{}
+	for _, file := range []string{scopeFileIPv4, scopeFileIPv6, scopeFileDomains, scopeFileExclude} {
+		for item := range s.scopeFileMap(file) {
+			err := validateScopeLine(file, item)
+			if err != nil {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckInvalidItem,
+					Severity: LintSeverityError,
+					File:     file,
+					Item:     item,
+					Message:  err.Error(),
+				})
+				continue
+			}
+
+			prefix, err := netip.ParsePrefix(item)
+			if err == nil && prefix.Masked().String() != item {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckNonCanonicalCIDR,
+					Severity: LintSeverityInfo,
+					File:     file,
+					Item:     item,
+					Message:  fmt.Sprintf("host bits set, same as %s", prefix.Masked()),
+					Related:  prefix.Masked().String(),
+					Fixable:  true,
+				})
+			}
+		}
+	}
+
+	_, _, hostnames := getCIDRsIPsHostname(s.domains)
+	for _, hostname := range hostnames {
+		if validateScopeLine(scopeFileDomains, hostname) == nil && !hasPublicSuffix(hostname) {
+			findings = append(findings, LintFinding{
+				Check:    LintCheckInvalidSuffix,
+				Severity: LintSeverityError,
+				File:     scopeFileDomains,
+				Item:     hostname,
+				Message:  "no valid public suffix, subdomains will not be in scope",
+			})
+		}
+	}
+	return findings
+}
+
+func (s *Scope) lintRedundantIPs(file string, scopeMap map[string]bool) []LintFinding {
+	findings := []LintFinding{}
+	cidrs, ipAddrs, _ := getCIDRsIPsHostname(scopeMap)
+
+	for _, ipAddr := range ipAddrs {
+		addr, err := netip.ParseAddr(ipAddr)
+		if err != nil {
+			continue
+		}
+		for _, cidr := range cidrs {
+			if cidr.Contains(addr.AsSlice()) {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckRedundantIP,
+					Severity: LintSeverityInfo,
+					File:     file,
+					Item:     ipAddr,
+					Message:  fmt.Sprintf("already covered by %s", cidr),
+					Related:  cidr.String(),
+					Fixable:  true,
+				})
+				break
+			}
+		}
+	}
+
+	for _, cidr := range cidrs {
+		ones, _ := cidr.Mask.Size()
+		for _, other := range cidrs {
+			otherOnes, _ := other.Mask.Size()
+			if otherOnes < ones && other.Contains(cidr.IP) {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckRedundantIP,
+					Severity: LintSeverityInfo,
+					File:     file,
+					Item:     cidr.String(),
+					Message:  fmt.Sprintf("already covered by %s", other),
+					Related:  other.String(),
+					Fixable:  true,
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func (s *Scope) lintRedundantDomains() []LintFinding {
+	findings := []LintFinding{}
+	_, _, hostnames := getCIDRsIPsHostname(s.domains)
+	sort.Strings(hostnames)
+
+	for _, hostname := range hostnames {
+		// check parents from the top down to report the broadest one
+		labels := strings.Split(hostname, ".")
+		for i := len(labels) - 1; i > 0; i-- {
+			parent := strings.Join(labels[i:], ".")
+			if s.domains[parent] {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckRedundantDomain,
+					Severity: LintSeverityInfo,
+					File:     scopeFileDomains,
+					Item:     hostname,
+					Message:  fmt.Sprintf("already covered by %s", parent),
+					Related:  parent,
+					Fixable:  true,
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func (s *Scope) lintExcludedIncludes() []LintFinding {
+	findings := []LintFinding{}
+	excluded := s.ExcludedPrefixes()
+
+	for _, file := range []string{scopeFileIPv4, scopeFileIPv6} {
+		for item := range s.scopeFileMap(file) {
+			prefix, ok := itemPrefix(item)
+			if !ok {
+				continue
+			}
+			overlap := utils.IntersectPrefixes([]netip.Prefix{prefix}, excluded)
+			if len(overlap) == 1 && overlap[0] == prefix {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckExcludedInclude,
+					Severity: LintSeverityWarning,
+					File:     file,
+					Item:     item,
+					Message:  "entirely excluded, never in scope",
+					Fixable:  true,
+				})
+			}
+		}
+	}
+
+	excludedDomains := map[string]Explanation{}
+	for domain := range s.domains {
+		explanation := s.Explain(domain)
+		if explanation.Reason == "excluded" || explanation.Reason == "parent domain excluded" {
+			excludedDomains[domain] = explanation
+		}
+	}
+	// a listed domain also brings its root domain's subdomains into scope, so
+	// it can only be removed when another listed domain keeps that root, or
+	// the root is excluded anyway
+	keptRoots := map[string]bool{}
+	for domain := range s.domains {
+		if _, excluded := excludedDomains[domain]; !excluded {
+			rootDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
+			if err == nil {
+				keptRoots[rootDomain] = true
+			}
+		}
+	}
+	for _, domain := range sortedScopeKeys(excludedDomains) {
+		explanation := excludedDomains[domain]
+		rootDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
+		findings = append(findings, LintFinding{
+			Check:    LintCheckExcludedInclude,
+			Severity: LintSeverityWarning,
+			File:     scopeFileDomains,
+			Item:     domain,
+			Message:  fmt.Sprintf("excluded by %s, never in scope", explanation.Rule),
+			Related:  explanation.Rule,
+			Fixable:  err == nil && (keptRoots[rootDomain] || s.Explain(rootDomain).Reason == "excluded"),
+		})
+	}
+	return findings
+}
+
+func (s *Scope) lintUnusedExcludes() []LintFinding {
+	findings := []LintFinding{}
+	included := s.IncludedPrefixes()
+
+	for exclude := range s.excludes {
+		used := false
+		prefix, ok := itemPrefix(exclude)
+		if ok {
+			used = len(utils.IntersectPrefixes([]netip.Prefix{prefix}, included)) > 0
+		} else {
+			used = s.excludeMatchesDomain(exclude)
+		}
+
+		if !used {
+			findings = append(findings, LintFinding{
+				Check:    LintCheckUnusedExclude,
+				Severity: LintSeverityWarning,
+				File:     scopeFileExclude,
+				Item:     exclude,
+				Message:  "does not match anything in scope",
+			})
+		}
+	}
+	return findings
+}
+
+// excludeMatchesDomain reports whether an excluded hostname overlaps an
+// included domain. Subdomains of an included domain's registrable domain are
+// in scope, so sharing it is enough.
+func (s *Scope) excludeMatchesDomain(exclude string) bool {
+	excludeRoot, _ := publicsuffix.EffectiveTLDPlusOne(exclude)
+	for domain := range s.domains {
+		if domain == exclude || strings.HasSuffix(domain, "."+exclude) || strings.HasSuffix(exclude, "."+domain) {
+			return true
+		}
+		root, err := publicsuffix.EffectiveTLDPlusOne(domain)
+		if err == nil && root == excludeRoot {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) lintNonPublic() []LintFinding {
+	findings := []LintFinding{}
+	for _, file := range []string{scopeFileIPv4, scopeFileIPv6} {
+		for item := range s.scopeFileMap(file) {
+			prefix, ok := itemPrefix(item)
+			if !ok {
+				continue
+			}
+			nonPublic := []string{}
+			for _, class := range ClassifyPrefix(prefix) {
+				if class != AddressClassPublic {
+					nonPublic = append(nonPublic, class)
+				}
+			}
+			if len(nonPublic) > 0 {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckNonPublicExternal,
+					Severity: LintSeverityWarning,
+					File:     file,
+					Item:     item,
+					Message:  fmt.Sprintf("includes %s address space, not reachable from the internet", strings.Join(nonPublic, ", ")),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func (s *Scope) lintProviders(providers *ProviderRanges) []LintFinding {
+	findings := []LintFinding{}
+	for _, file := range []string{scopeFileIPv4, scopeFileIPv6} {
+		for item := range s.scopeFileMap(file) {
+			prefix, ok := itemPrefix(item)
+			if !ok {
+				continue
+			}
+			names := providers.Lookup(prefix)
+			if len(names) > 0 {
+				findings = append(findings, LintFinding{
+					Check:    LintCheckProviderRange,
+					Severity: LintSeverityInfo,
+					File:     file,
+					Item:     item,
+					Message:  fmt.Sprintf("belongs to %s, check testing is authorised by the provider", strings.Join(names, ", ")),
+					Related:  strings.Join(names, ","),
+				})
+			}
+		}
+	}
+	return findings
+}
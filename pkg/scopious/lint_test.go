@@ -0,0 +1,108 @@
+package scopious
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScope_Lint(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.ipv4["203.0.113.0/24"] = true
+	s.ipv4["203.0.113.7"] = true
+	s.ipv4["203.0.113.64/26"] = true
+	s.ipv4["198.51.100.1/24"] = true
+	s.ipv4["10.0.0.0/8"] = true
+	s.ipv4["104.16.0.1"] = true
+	s.ipv4["192.0.2.9"] = true
+	s.ipv4["not an ip"] = true
+	s.domains["example.com"] = true
+	s.domains["www.example.com"] = true
+	s.domains["admin.example.org"] = true
+	s.domains["intranet.corp"] = true
+	s.AddExclude("192.0.2.0/28", "example.org", "example.net", "203.0.113.5")
+
+	want := map[string]string{
+		"203.0.113.7":       LintCheckRedundantIP,
+		"203.0.113.64/26":   LintCheckRedundantIP,
+		"198.51.100.1/24":   LintCheckNonCanonicalCIDR,
+		"192.0.2.9":         LintCheckExcludedInclude,
+		"not an ip":         LintCheckInvalidItem,
+		"www.example.com":   LintCheckRedundantDomain,
+		"admin.example.org": LintCheckExcludedInclude,
+		"intranet.corp":     LintCheckInvalidSuffix,
+		"example.net":       LintCheckUnusedExclude,
+	}
+
+	findings := s.Lint(LintOptions{})
+	got := map[string]string{}
+	for _, finding := range findings {
+		got[finding.Item] = finding.Check
+	}
+	for item, check := range want {
+		if got[item] != check {
+			t.Errorf("Lint() %s = %q, want %q", item, got[item], check)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Lint() = %+v", findings)
+	}
+
+	external := map[string]string{}
+	for _, finding := range s.Lint(LintOptions{External: true, Providers: BundledProviderRanges()}) {
+		if finding.Check == LintCheckNonPublicExternal || finding.Check == LintCheckProviderRange {
+			external[finding.Item] = finding.Message
+		}
+	}
+	if external["10.0.0.0/8"] != "includes private address space, not reachable from the internet" ||
+		external["203.0.113.0/24"] != "includes documentation address space, not reachable from the internet" ||
+		external["104.16.0.1"] != "belongs to cloudflare, check testing is authorised by the provider" {
+		t.Errorf("Lint(external) = %v", external)
+	}
+
+	fixed := s.Fix(findings)
+	if fixed != 6 {
+		t.Errorf("Fix() = %d, want 6", fixed)
+	}
+	if !s.ipv4["198.51.100.0/24"] || s.ipv4["203.0.113.7"] || s.domains["www.example.com"] || s.domains["admin.example.org"] {
+		t.Errorf("Fix() IPv4 = %v, Domains = %v", s.ipv4, s.domains)
+	}
+	if !s.IsInScope("203.0.113.7") || !s.IsInScope("www.example.com") {
+		t.Errorf("Fix() changed what is in scope")
+	}
+}
+
+func TestScope_Fix_ExcludedInclude(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.Add("admin.example.com", "dev.example.org", "api.example.org", "203.0.113.0/24")
+	s.AddExclude("admin.example.com", "dev.example.org")
+
+	items := []string{"www.example.com", "admin.example.com", "www.example.org", "dev.example.org", "api.example.org", "203.0.113.5"}
+	before := map[string]bool{}
+	matcher := s.Compile()
+	for _, item := range items {
+		before[item] = matcher.Contains(item)
+	}
+
+	fixable := map[string]bool{}
+	findings := s.Lint(LintOptions{})
+	for _, finding := range findings {
+		if finding.Check == LintCheckExcludedInclude {
+			fixable[finding.Item] = finding.Fixable
+		}
+	}
+	// admin.example.com is the only domain keeping example.com subdomains in scope
+	want := map[string]bool{"admin.example.com": false, "dev.example.org": true}
+	if !reflect.DeepEqual(fixable, want) {
+		t.Errorf("Lint() fixable = %v, want %v", fixable, want)
+	}
+
+	s.Fix(findings)
+	after := map[string]bool{}
+	matcher = s.Compile()
+	for _, item := range items {
+		after[item] = matcher.Contains(item)
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("Fix() changed what is in scope from %v to %v", before, after)
+	}
+}
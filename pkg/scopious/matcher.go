@@ -0,0 +1,226 @@
+package scopious
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+// Matcher is a compiled, read only view of a scope for checking items. It is
+// safe for concurrent use and isn't affected by later changes to the scope it
+// was compiled from.
+type Matcher struct {
+	included          []netip.Prefix
+	excluded          []netip.Prefix
+	effective         []netip.Prefix
+	domains           map[string]bool
+	rootDomains       []string
+	excludedHostnames map[string]bool
+}
+
+// MatcherBuilder builds a Matcher in memory, without a scope directory.
+type MatcherBuilder struct {
+	includes []string
+	excludes []string
+}
+
+// NewMatcher starts building a Matcher from scope items.
+func NewMatcher() *MatcherBuilder {
+	return &MatcherBuilder{}
+}
+
+// Include adds IP addresses, CIDRs, URLs and domains to the scope.
+func (b *MatcherBuilder) Include(items ...string) *MatcherBuilder {
+	b.includes = append(b.includes, items...)
+	return b
+}
+
+// Exclude excludes IP addresses, CIDRs, URLs and domains from the scope.
+func (b *MatcherBuilder) Exclude(items ...string) *MatcherBuilder {
+	b.excludes = append(b.excludes, items...)
+	return b
+}
+
+// Compile builds the Matcher. Items are matched exactly as they would be had
+// they been added to a scope on disk, whatever order they were given in.
+func (b *MatcherBuilder) Compile() *Matcher {
+	scope := NewScopeFromPath("")
+	scope.Import(ImportResult{Includes: b.includes, Excludes: b.excludes})
+	return scope.Compile()
+}
+
+// Compile snapshots the scope into a Matcher.
+func (s *Scope) Compile() *Matcher {
+	if s.excludedHostnames == nil {
+		s.populateExcludes()
+	}
+
+	m := &Matcher{
+		included:          s.IncludedPrefixes(),
+		excluded:          s.ExcludedPrefixes(),
+		domains:           map[string]bool{},
+		excludedHostnames: map[string]bool{},
+	}
+	m.effective = utils.SubtractPrefixes(m.included, m.excluded)
+	for domain := range s.domains {
+		m.domains[domain] = true
+	}
+	for hostname := range s.excludedHostnames {
+		m.excludedHostnames[hostname] = true
+	}
+	// root domains that are excluded don't bring their subdomains into scope
+	m.rootDomains = sortedScopeKeys(s.getRootDomainMap(m))
+	return m
+}
+
+// EffectivePrefixes returns the address space in scope once excludes are
+// applied.
+func (m *Matcher) EffectivePrefixes() []netip.Prefix {
+	return append([]netip.Prefix{}, m.effective...)
+}
+
+// ContainsAddr reports whether addr is in scope.
+func (m *Matcher) ContainsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	// effective prefixes are sorted and don't overlap
+	i := sort.Search(len(m.effective), func(i int) bool {
+		return addr.Less(m.effective[i].Addr())
+	})
+	return i > 0 && m.effective[i-1].Contains(addr)
+}
+
+// ContainsPrefix reports whether every address in prefix is in scope.
+func (m *Matcher) ContainsPrefix(prefix netip.Prefix) bool {
+	if !prefix.IsValid() {
+		return false
+	}
+	prefix = prefix.Masked()
+	return len(utils.SubtractPrefixes([]netip.Prefix{prefix}, m.effective)) == 0
+}
+
+// ExcludesAddr reports whether addr has been excluded, whether or not it was
+// in scope to begin with.
+func (m *Matcher) ExcludesAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, exclude := range m.excluded {
+		if exclude.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsDomain reports whether domain is in scope. Subdomains of an in
+// scope domain's registrable domain are in scope unless they, or a parent,
+// are excluded.
+func (m *Matcher) ContainsDomain(domain string) bool {
+	explanation := Explanation{}
+	m.explainDomain(&explanation, strings.ToLower(strings.TrimSuffix(domain, ".")))
+	return explanation.InScope
+}
+
+// ExcludesDomain reports whether domain or one of its parents has been
+// excluded.
+func (m *Matcher) ExcludesDomain(domain string) bool {
+	explanation := Explanation{}
+	m.explainDomain(&explanation, strings.ToLower(strings.TrimSuffix(domain, ".")))
+	return explanation.Reason == "excluded" || explanation.Reason == "parent domain excluded"
+}
+
+// Contains reports whether an IP address, CIDR, URL or domain is in scope.
+// CIDRs are in scope when every address in them is.
+func (m *Matcher) Contains(item string) bool {
+	return m.Explain(item).InScope
+}
+
+// Explain reports whether item is in scope along with the rule responsible.
+// Excludes are checked first as they take precedence.
+func (m *Matcher) Explain(item string) Explanation {
+	explanation := Explanation{
+		Item:       item,
+		Normalized: normalizedScope(item),
+	}
+
+	prefix, ok := scopeItemPrefix(item)
+	if ok {
+		explanation.Kind = ExplainKindCIDR
+		explanation.Normalized = prefix.String()
+		if prefix.IsSingleIP() {
+			explanation.Kind = ExplainKindIP
+			explanation.Normalized = prefix.Addr().String()
+		}
+		m.explainPrefix(&explanation, prefix)
+		return explanation
+	}
+
+	if explanation.Normalized == "" {
+		explanation.Kind = ExplainKindInvalid
+		explanation.Reason = "not a valid IP address, CIDR, URL or domain"
+		return explanation
+	}
+
+	explanation.Kind = ExplainKindDomain
+	m.explainDomain(&explanation, explanation.Normalized)
+	return explanation
+}
+
+func (m *Matcher) explainPrefix(explanation *Explanation, prefix netip.Prefix) {
+	for _, exclude := range m.excluded {
+		if exclude.Overlaps(prefix) {
+			explanation.Reason = "excluded"
+			explanation.Rule = exclude.String()
+			if exclude.Bits() > prefix.Bits() {
+				explanation.Reason = "partly excluded"
+			}
+			return
+		}
+	}
+
+	for _, include := range m.included {
+		if include.Bits() <= prefix.Bits() && include.Contains(prefix.Addr()) {
+			explanation.InScope = true
+			explanation.Reason = "included"
+			explanation.Rule = include.String()
+			if include.IsSingleIP() {
+				explanation.Rule = include.Addr().String()
+			}
+			return
+		}
+	}
+
+	explanation.Reason = "not in IP scope"
+}
+
+func (m *Matcher) explainDomain(explanation *Explanation, domain string) {
+	if m.excludedHostnames[domain] {
+		explanation.Reason = "excluded"
+		explanation.Rule = domain
+		return
+	}
+	for excluded := range m.excludedHostnames {
+		if strings.HasSuffix(domain, "."+excluded) {
+			explanation.Reason = "parent domain excluded"
+			explanation.Rule = excluded
+			return
+		}
+	}
+
+	if m.domains[domain] {
+		explanation.InScope = true
+		explanation.Reason = "included"
+		explanation.Rule = domain
+		return
+	}
+	for _, rootDomain := range m.rootDomains {
+		if strings.HasSuffix(domain, "."+rootDomain) {
+			explanation.InScope = true
+			explanation.Reason = "subdomain of an in scope domain"
+			explanation.Rule = rootDomain
+			return
+		}
+	}
+
+	explanation.Reason = "not in domain scope"
+}
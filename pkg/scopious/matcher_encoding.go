@@ -0,0 +1,191 @@
+package scopious
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+// matcherEncodingVersion is bumped whenever the matching rules or the encoded
+// forms change, so agents refuse matchers they would interpret differently.
+const matcherEncodingVersion = 1
+
+var matcherBinaryMagic = []byte("SCPM")
+
+type matcherJSON struct {
+	Version           int            `json:"version"`
+	Included          []netip.Prefix `json:"included"`
+	Excluded          []netip.Prefix `json:"excluded"`
+	Domains           []string       `json:"domains"`
+	RootDomains       []string       `json:"root_domains"`
+	ExcludedHostnames []string       `json:"excluded_hostnames"`
+}
+
+// MarshalJSON encodes the compiled rules, so a matcher can be shipped to
+// agents that don't have the scope files.
+func (m *Matcher) MarshalJSON() ([]byte, error) {
+	return json.Marshal(matcherJSON{
+		Version:           matcherEncodingVersion,
+		Included:          m.included,
+		Excluded:          m.excluded,
+		Domains:           sortedScopeKeys(m.domains),
+		RootDomains:       m.rootDomains,
+		ExcludedHostnames: sortedScopeKeys(m.excludedHostnames),
+	})
+}
+
+// UnmarshalJSON decodes a matcher encoded by MarshalJSON. It replaces the
+// matcher's rules, so only use it on a new Matcher.
+func (m *Matcher) UnmarshalJSON(data []byte) error {
+	var encoded matcherJSON
+	err := json.Unmarshal(data, &encoded)
+	if err != nil {
+		return err
+	}
+	if encoded.Version != matcherEncodingVersion {
+		return fmt.Errorf("unsupported matcher version %d", encoded.Version)
+	}
+	m.setRules(encoded)
+	return nil
+}
+
+// MarshalBinary encodes the compiled rules more compactly than MarshalJSON.
+// Prefixes only store the bytes covered by their mask.
+func (m *Matcher) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(matcherBinaryMagic)
+	buf.WriteByte(matcherEncodingVersion)
+	for _, prefixes := range [][]netip.Prefix{m.included, m.excluded} {
+		writeUvarint(&buf, uint64(len(prefixes)))
+		for _, prefix := range prefixes {
+			addr := prefix.Addr().AsSlice()
+			buf.WriteByte(byte(len(addr)))
+			buf.WriteByte(byte(prefix.Bits()))
+			buf.Write(addr[:(prefix.Bits()+7)/8])
+		}
+	}
+	for _, items := range [][]string{sortedScopeKeys(m.domains), m.rootDomains, sortedScopeKeys(m.excludedHostnames)} {
+		writeUvarint(&buf, uint64(len(items)))
+		for _, item := range items {
+			writeUvarint(&buf, uint64(len(item)))
+			buf.WriteString(item)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a matcher encoded by MarshalBinary. It replaces the
+// matcher's rules, so only use it on a new Matcher.
+func (m *Matcher) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	magic := make([]byte, len(matcherBinaryMagic))
+	_, err := io.ReadFull(r, magic)
+	if err != nil || !bytes.Equal(magic, matcherBinaryMagic) {
+		return errors.New("not an encoded matcher")
+	}
+	version, err := r.ReadByte()
+	if err != nil {
+		return errTruncatedMatcher
+	}
+	if version != matcherEncodingVersion {
+		return fmt.Errorf("unsupported matcher version %d", version)
+	}
+
+	encoded := matcherJSON{Version: int(version)}
+	for _, prefixes := range []*[]netip.Prefix{&encoded.Included, &encoded.Excluded} {
+		*prefixes, err = readPrefixes(r)
+		if err != nil {
+			return err
+		}
+	}
+	for _, items := range []*[]string{&encoded.Domains, &encoded.RootDomains, &encoded.ExcludedHostnames} {
+		*items, err = readStrings(r)
+		if err != nil {
+			return err
+		}
+	}
+	if r.Len() > 0 {
+		return errors.New("trailing data after encoded matcher")
+	}
+	m.setRules(encoded)
+	return nil
+}
+
+var errTruncatedMatcher = errors.New("truncated matcher")
+
+func (m *Matcher) setRules(encoded matcherJSON) {
+	m.included = utils.AggregatePrefixes(encoded.Included)
+	m.excluded = utils.AggregatePrefixes(encoded.Excluded)
+	m.effective = utils.SubtractPrefixes(m.included, m.excluded)
+	m.domains = map[string]bool{}
+	for _, domain := range encoded.Domains {
+		m.domains[domain] = true
+	}
+	m.rootDomains = append([]string{}, encoded.RootDomains...)
+	sort.Strings(m.rootDomains)
+	m.excludedHostnames = map[string]bool{}
+	for _, hostname := range encoded.ExcludedHostnames {
+		m.excludedHostnames[hostname] = true
+	}
+}
+
+func writeUvarint(buf *bytes.Buffer, value uint64) {
+	buf.Write(binary.AppendUvarint(nil, value))
+}
+
+func readPrefixes(r *bytes.Reader) ([]netip.Prefix, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(r.Len()) {
+		return nil, errTruncatedMatcher
+	}
+
+	prefixes := []netip.Prefix{}
+	for i := uint64(0); i < count; i++ {
+		size, err := r.ReadByte()
+		if err != nil {
+			return nil, errTruncatedMatcher
+		}
+		bits, err := r.ReadByte()
+		if err != nil {
+			return nil, errTruncatedMatcher
+		}
+		if size != 4 && size != 16 || int(bits) > int(size)*8 {
+			return nil, fmt.Errorf("invalid prefix length /%d", bits)
+		}
+
+		addr := make([]byte, size)
+		_, err = io.ReadFull(r, addr[:(int(bits)+7)/8])
+		if err != nil {
+			return nil, errTruncatedMatcher
+		}
+		ip, _ := netip.AddrFromSlice(addr)
+		prefixes = append(prefixes, netip.PrefixFrom(ip, int(bits)).Masked())
+	}
+	return prefixes, nil
+}
+
+func readStrings(r *bytes.Reader) ([]string, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(r.Len()) {
+		return nil, errTruncatedMatcher
+	}
+
+	items := []string{}
+	for i := uint64(0); i < count; i++ {
+		length, err := binary.ReadUvarint(r)
+		if err != nil || length > uint64(r.Len()) {
+			return nil, errTruncatedMatcher
+		}
+		item := make([]byte, length)
+		io.ReadFull(r, item)
+		items = append(items, string(item))
+	}
+	return items, nil
+}
@@ -0,0 +1,141 @@
+package scopious
+
+import (
+	"encoding/json"
+	"net/netip"
+	"path/filepath"
+	"testing"
+)
+
+var matcherTestItems = []string{
+	"example.com", "www.example.com", "admin.example.com", "vpn.admin.example.com",
+	"api.example.net", "www.example.net", "example.org",
+	"203.0.113.1", "203.0.113.130", "203.0.113.0/25", "203.0.113.0/24", "198.51.100.7", "198.51.100.8",
+	"2001:db8::1", "2001:db8::ff", "2001:db8::/121",
+}
+
+func TestMatcher_MatchesIsInScope(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.Add("example.com", "api.example.net", "203.0.113.0/24", "198.51.100.7", "2001:db8::/120")
+	s.AddExclude("admin.example.com", "203.0.113.128/26", "2001:db8::ff")
+	matcher := s.Compile()
+
+	for _, item := range matcherTestItems {
+		if got, want := matcher.Contains(item), s.IsInScope(item); got != want {
+			t.Errorf("Contains(%s) = %v, IsInScope = %v", item, got, want)
+		}
+	}
+	// URLs, trailing dots and ports are checked by their host
+	forms := map[string]bool{
+		"https://www.example.com/login": true,
+		"www.example.com.":              true,
+		"www.example.com:8443":          true,
+		"https://admin.example.com/":    false,
+		"203.0.113.5:443":               true,
+	}
+	for item, want := range forms {
+		if got := s.IsInScope(item); got != want {
+			t.Errorf("IsInScope(%s) = %v, want %v", item, got, want)
+		}
+	}
+
+	if !matcher.ExcludesAddr(netip.MustParseAddr("203.0.113.129")) || matcher.ExcludesAddr(netip.MustParseAddr("192.0.2.1")) {
+		t.Error("ExcludesAddr does not match the excluded prefixes")
+	}
+	if !matcher.ExcludesDomain("vpn.admin.example.com") || matcher.ExcludesDomain("www.example.com") {
+		t.Error("ExcludesDomain does not match the excluded hostnames")
+	}
+
+	// later changes to the scope don't affect the compiled matcher
+	s.Add("example.org")
+	if matcher.Contains("example.org") {
+		t.Error("matcher changed after compiling")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "scope")
+	_, err := Open(dir)
+	if err == nil {
+		t.Fatal("expected an error opening a missing scope directory")
+	}
+
+	scoper, err := Open(dir, WithCreate(), WithDefaultScope("external"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := scoper.Lookup("external"); !ok || len(scoper.Names()) != 1 {
+		t.Errorf("Names() = %v, want the default scope", scoper.Names())
+	}
+}
+
+func TestMatcherBuilder(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.AddExclude("admin.example.com", "203.0.113.128/26", "2001:db8::ff")
+	s.Add("example.com", "api.example.net", "203.0.113.0/24", "198.51.100.7", "2001:db8::/120")
+	fromScope := s.Compile()
+
+	// excludes given after includes still apply
+	built := NewMatcher().
+		Include("example.com", "api.example.net", "203.0.113.0/24").
+		Include("198.51.100.7", "2001:db8::/120").
+		Exclude("admin.example.com", "203.0.113.128/26", "2001:db8::ff").
+		Compile()
+
+	for _, item := range matcherTestItems {
+		if got, want := built.Contains(item), fromScope.Contains(item); got != want {
+			t.Errorf("built Contains(%s) = %v, want %v", item, got, want)
+		}
+	}
+}
+
+func TestMatcher_Encoding(t *testing.T) {
+	matcher := NewMatcher().
+		Include("example.com", "api.example.net", "203.0.113.0/24", "198.51.100.7", "2001:db8::/120").
+		Exclude("admin.example.com", "203.0.113.128/26", "2001:db8::ff").
+		Compile()
+
+	jsonData, err := json.Marshal(matcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryData, err := matcher.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binaryData) >= len(jsonData) {
+		t.Errorf("binary encoding is %d bytes, JSON is %d", len(binaryData), len(jsonData))
+	}
+
+	fromJSON := &Matcher{}
+	err = json.Unmarshal(jsonData, fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBinary := &Matcher{}
+	err = fromBinary.UnmarshalBinary(binaryData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range matcherTestItems {
+		want := matcher.Explain(item)
+		if got := fromJSON.Explain(item); got != want {
+			t.Errorf("JSON Explain(%s) = %+v, want %+v", item, got, want)
+		}
+		if got := fromBinary.Explain(item); got != want {
+			t.Errorf("binary Explain(%s) = %+v, want %+v", item, got, want)
+		}
+	}
+
+	for i := range binaryData {
+		err = (&Matcher{}).UnmarshalBinary(binaryData[:i])
+		if err == nil {
+			t.Fatalf("expected an error decoding %d of %d bytes", i, len(binaryData))
+		}
+	}
+	err = json.Unmarshal([]byte(`{"version": 99}`), &Matcher{})
+	if err == nil {
+		t.Error("expected an error decoding an unknown version")
+	}
+}
@@ -0,0 +1,246 @@
+package scopious
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/analog-substance/util/fileutil"
+	"golang.org/x/net/publicsuffix"
+)
+
+// How a candidate relates to the scope it was proposed for.
+const (
+	CandidateInScope   = "in-scope"
+	CandidateExcluded  = "excluded"
+	CandidateAdjacent  = "adjacent"
+	CandidateUnrelated = "unrelated"
+)
+
+// Candidate is a discovered item waiting to be approved into scope or
+// rejected, so discoveries are kept without changing what is in scope.
+type Candidate struct {
+	Item string `json:"item"`
+	// Source is where the item was discovered, like the tool that found it.
+	Source   string    `json:"source,omitempty"`
+	Proposed time.Time `json:"proposed"`
+}
+
+// CandidateReview is a candidate along with how it relates to the scope.
+type CandidateReview struct {
+	Candidate
+	Relation string `json:"relation"`
+	Reason   string `json:"reason"`
+	// Rule is the scope item the candidate relates to, if any.
+	Rule string `json:"rule,omitempty"`
+}
+
+// Propose adds discovered items to the pending list, returning the items
+// added. Items already listed in scope, excluded or pending are skipped.
+func (s *Scope) Propose(source string, scopeItems ...string) []string {
+	if s.pending == nil {
+		s.pending = map[string]Candidate{}
+	}
+	source = strings.Join(strings.Fields(source), " ")
+
+	proposed := []string{}
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, scopeItem := range scopeItems {
+		// pending items are lowercased when read back
+		scopeItem = strings.ToLower(normalizedScope(scopeItem))
+		if scopeItem == "" || s.ipv4[scopeItem] || s.ipv6[scopeItem] || s.domains[scopeItem] || s.excludes[scopeItem] {
+			continue
+		}
+		if _, ok := s.pending[scopeItem]; ok {
+			continue
+		}
+		if strings.Contains(scopeItem, "/") {
+			_, _, err := net.ParseCIDR(scopeItem)
+			if err != nil {
+				continue
+			}
+		}
+
+		s.pending[scopeItem] = Candidate{Item: scopeItem, Source: source, Proposed: now}
+		proposed = append(proposed, scopeItem)
+	}
+	return proposed
+}
+
+// Pending returns the candidates waiting for review.
+func (s *Scope) Pending() []Candidate {
+	candidates := []Candidate{}
+	for _, item := range sortedScopeKeys(s.pending) {
+		candidates = append(candidates, s.pending[item])
+	}
+	return candidates
+}
+
+// Approve moves pending items into scope, noting where they were discovered.
+// It returns the items added. Items that are excluded can't be added, they
+// are left pending and returned in the error.
+func (s *Scope) Approve(scopeItems ...string) ([]string, error) {
+	approved := []string{}
+	errs := []error{}
+	for _, candidate := range s.takePending(scopeItems) {
+		s.Add(candidate.Item)
+		if !s.ipv4[candidate.Item] && !s.ipv6[candidate.Item] && !s.domains[candidate.Item] {
+			s.pending[candidate.Item] = candidate
+			errs = append(errs, fmt.Errorf("%s is excluded, it was left pending", candidate.Item))
+			continue
+		}
+		if candidate.Source != "" && s.notes[candidate.Item] == "" {
+			s.SetNote(candidate.Item, "discovered by "+candidate.Source)
+		}
+		approved = append(approved, candidate.Item)
+	}
+	return approved, errors.Join(errs...)
+}
+
+// Reject moves pending items to the exclude list. It returns the items that
+// were pending.
+func (s *Scope) Reject(scopeItems ...string) []string {
+	rejected := s.takePending(scopeItems)
+	for _, candidate := range rejected {
+		s.AddExclude(candidate.Item)
+	}
+	return candidateItems(rejected)
+}
+
+func (s *Scope) takePending(scopeItems []string) []Candidate {
+	taken := []Candidate{}
+	for _, scopeItem := range scopeItems {
+		scopeItem = strings.ToLower(normalizedScope(scopeItem))
+		candidate, ok := s.pending[scopeItem]
+		if ok {
+			delete(s.pending, scopeItem)
+			taken = append(taken, candidate)
+		}
+	}
+	return taken
+}
+
+func candidateItems(candidates []Candidate) []string {
+	items := []string{}
+	for _, candidate := range candidates {
+		items = append(items, candidate.Item)
+	}
+	return items
+}
+
+// Review classifies the pending candidates by how they relate to the scope.
+// Candidates already covered by scope, like subdomains of an in scope domain,
+// are in-scope. Domains sharing a name with an in scope domain under another
+// suffix are adjacent, as are addresses announced by the same autonomous
+// system as in scope address space when an ASN database is given.
+func (s *Scope) Review(asnDB *ASNDatabase) []CandidateReview {
+	matcher := s.Compile()
+
+	rootDomainNames := map[string]string{}
+	for _, rootDomain := range matcher.rootDomains {
+		rootDomainNames[registrableName(rootDomain)] = rootDomain
+	}
+	asnPrefixes := map[uint32]string{}
+	if asnDB != nil {
+		for _, prefix := range matcher.effective {
+			for _, record := range asnDB.LookupPrefix(prefix) {
+				if _, ok := asnPrefixes[record.ASN]; !ok {
+					asnPrefixes[record.ASN] = prefix.String()
+				}
+			}
+		}
+	}
+
+	reviews := []CandidateReview{}
+	for _, candidate := range s.Pending() {
+		review := CandidateReview{Candidate: candidate}
+		explanation := matcher.Explain(candidate.Item)
+		review.Relation = CandidateUnrelated
+		review.Reason = explanation.Reason
+		review.Rule = explanation.Rule
+
+		switch {
+		case explanation.InScope:
+			review.Relation = CandidateInScope
+		case strings.Contains(explanation.Reason, "excluded"):
+			review.Relation = CandidateExcluded
+		case explanation.Kind == ExplainKindDomain:
+			rootDomain, ok := rootDomainNames[registrableName(explanation.Normalized)]
+			if ok {
+				review.Relation = CandidateAdjacent
+				review.Reason = "same name as an in scope domain"
+				review.Rule = rootDomain
+			}
+		case asnDB != nil && explanation.Kind != ExplainKindInvalid:
+			for _, record := range asnDB.LookupItem(candidate.Item) {
+				prefix, ok := asnPrefixes[record.ASN]
+				if ok {
+					review.Relation = CandidateAdjacent
+					review.Reason = fmt.Sprintf("announced by AS%d like in scope address space", record.ASN)
+					review.Rule = prefix
+					break
+				}
+			}
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews
+}
+
+// registrableName is the label before the public suffix, example for both
+// www.example.com and example.co.uk.
+func registrableName(domain string) string {
+	rootDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return ""
+	}
+	name, _, _ := strings.Cut(rootDomain, ".")
+	return name
+}
+
+// readPending reads tab separated item, source and proposed time rows.
+func readPending(path string) (map[string]Candidate, error) {
+	pending := map[string]Candidate{}
+	lines, err := fileutil.ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		item := strings.ToLower(strings.TrimSpace(fields[0]))
+		if item == "" {
+			continue
+		}
+
+		candidate := Candidate{Item: item}
+		if len(fields) > 1 {
+			candidate.Source = strings.TrimSpace(fields[1])
+		}
+		if len(fields) > 2 {
+			candidate.Proposed, err = time.Parse(time.RFC3339, strings.TrimSpace(fields[2]))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		pending[item] = candidate
+	}
+	return pending, nil
+}
+
+// writePending writes the pending list, removing the file once it is empty.
+func writePending(path string, pending map[string]Candidate) error {
+	if len(pending) == 0 {
+		return removeScopeFile(path)
+	}
+
+	lines := []string{}
+	for _, item := range sortedScopeKeys(pending) {
+		candidate := pending[item]
+		lines = append(lines, strings.Join([]string{item, candidate.Source, candidate.Proposed.Format(time.RFC3339)}, "\t"))
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,119 @@
+package scopious
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScope_Review(t *testing.T) {
+	db, err := LoadASNDatabase(filepath.Join("testdata", "asn", "ip2asn-combined.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScopeFromPath("")
+	s.Add("example.com", "192.0.2.0/25")
+	s.AddExclude("admin.example.com")
+
+	proposed := s.Propose("subfinder", "www.example.com", "example.com", "vpn.admin.example.com", "example.co.uk",
+		"unrelated.net", "192.0.2.10", "203.0.113.5", "198.51.100.200", "  ")
+	want := []string{"www.example.com", "vpn.admin.example.com", "example.co.uk", "unrelated.net", "192.0.2.10", "203.0.113.5", "198.51.100.200"}
+	if !reflect.DeepEqual(proposed, want) {
+		t.Errorf("Propose() = %v, want %v", proposed, want)
+	}
+	if again := s.Propose("amass", "www.example.com"); len(again) != 0 {
+		t.Errorf("Propose() proposed %v again", again)
+	}
+
+	relations := map[string]string{}
+	rules := map[string]string{}
+	for _, review := range s.Review(db) {
+		relations[review.Item] = review.Relation
+		rules[review.Item] = review.Rule
+		if review.Source != "subfinder" {
+			t.Errorf("%s Source = %s, want subfinder", review.Item, review.Source)
+		}
+	}
+	wantRelations := map[string]string{
+		"www.example.com":       CandidateInScope,
+		"192.0.2.10":            CandidateInScope,
+		"vpn.admin.example.com": CandidateExcluded,
+		"example.co.uk":         CandidateAdjacent,
+		"203.0.113.5":           CandidateAdjacent,
+		"unrelated.net":         CandidateUnrelated,
+		"198.51.100.200":        CandidateUnrelated,
+	}
+	if !reflect.DeepEqual(relations, wantRelations) {
+		t.Errorf("Review() relations = %v, want %v", relations, wantRelations)
+	}
+	if rules["example.co.uk"] != "example.com" || rules["203.0.113.5"] != "192.0.2.0/25" {
+		t.Errorf("Review() rules = %v", rules)
+	}
+
+	withoutASN := s.Review(nil)
+	for _, review := range withoutASN {
+		if review.Item == "203.0.113.5" && review.Relation != CandidateUnrelated {
+			t.Errorf("203.0.113.5 is %s without an ASN database", review.Relation)
+		}
+	}
+}
+
+func TestScope_ApproveReject(t *testing.T) {
+	dir := t.TempDir()
+	scoper := openScoper(t, dir)
+	s := scoper.GetScope("external")
+	s.Add("example.com")
+	s.AddExclude("internal.example.com")
+	s.Propose("subfinder", "WWW.Example.com", "dev.example.com", "example.org", "vpn.internal.example.com")
+	err := scoper.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s = openScoper(t, dir).GetScope("external")
+	if got := len(s.Pending()); got != 4 {
+		t.Fatalf("loaded %d pending candidates, want 4", got)
+	}
+	if s.Pending()[0].Proposed.IsZero() {
+		t.Error("proposed time was not kept")
+	}
+
+	got, err := s.Approve("www.example.com", "not-pending.example.com")
+	if err != nil || !reflect.DeepEqual(got, []string{"www.example.com"}) {
+		t.Errorf("Approve() = %v, %v", got, err)
+	}
+	// excluded items can't be approved and are left pending
+	got, err = s.Approve("vpn.internal.example.com")
+	if err == nil || len(got) != 0 {
+		t.Errorf("Approve() = %v, %v, want an error", got, err)
+	}
+	if pending := s.Pending(); len(pending) != 3 || pending[2].Item != "vpn.internal.example.com" {
+		t.Errorf("Pending() = %v", pending)
+	}
+	if got := s.Reject("dev.example.com"); !reflect.DeepEqual(got, []string{"dev.example.com"}) {
+		t.Errorf("Reject() = %v", got)
+	}
+	if !reflect.DeepEqual(s.AllDomains(), []string{"example.com", "www.example.com"}) || !reflect.DeepEqual(s.Excludes(), []string{"dev.example.com", "internal.example.com"}) {
+		t.Errorf("domains = %v, excludes = %v", s.AllDomains(), s.Excludes())
+	}
+	if s.Note("www.example.com") != "discovered by subfinder" {
+		t.Errorf("Note() = %q", s.Note("www.example.com"))
+	}
+
+	// adding a pending item directly takes it off the pending list
+	s.Add("example.org")
+	s.Reject("vpn.internal.example.com")
+	err = s.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Pending()) != 0 {
+		t.Errorf("Pending() = %v, want none", s.Pending())
+	}
+	_, err = os.Stat(filepath.Join(dir, "external", scopeFilePending))
+	if !os.IsNotExist(err) {
+		t.Errorf("pending file left behind once empty: %v", err)
+	}
+}
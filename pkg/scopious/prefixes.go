@@ -0,0 +1,60 @@
+package scopious
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+// itemPrefix parses a scope item as a CIDR or single IP address prefix.
+func itemPrefix(item string) (netip.Prefix, bool) {
+	prefix, err := netip.ParsePrefix(item)
+	if err == nil {
+		return prefix.Masked(), true
+	}
+
+	addr, err := netip.ParseAddr(item)
+	if err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// scopeItemPrefix parses an IP address or CIDR as typed by a user, falling
+// back to normalizing it when it's written as a URL or host and port.
+func scopeItemPrefix(item string) (netip.Prefix, bool) {
+	prefix, ok := itemPrefix(strings.TrimSpace(item))
+	if ok {
+		return prefix, true
+	}
+	return itemPrefix(normalizedScope(item))
+}
+
+func itemPrefixes(items map[string]bool) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for item := range items {
+		prefix, ok := itemPrefix(item)
+		if ok {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// IncludedPrefixes returns the aggregated IPv4 and IPv6 address space added
+// to the scope, before excludes are applied.
+func (s *Scope) IncludedPrefixes() []netip.Prefix {
+	return utils.AggregatePrefixes(append(itemPrefixes(s.ipv4), itemPrefixes(s.ipv6)...))
+}
+
+// ExcludedPrefixes returns the aggregated address space that has been excluded.
+func (s *Scope) ExcludedPrefixes() []netip.Prefix {
+	return utils.AggregatePrefixes(itemPrefixes(s.excludes))
+}
+
+// EffectivePrefixes returns the address space in scope after excludes are applied.
+func (s *Scope) EffectivePrefixes() []netip.Prefix {
+	return utils.SubtractPrefixes(s.IncludedPrefixes(), s.ExcludedPrefixes())
+}
@@ -0,0 +1,259 @@
+package scopious
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+// ProviderRangesFile is where updated provider ranges are kept in the scope
+// directory.
+const ProviderRangesFile = "providers.tsv"
+
+//go:embed providers.tsv
+var bundledProviderRanges []byte
+
+// ProviderRanges maps address ranges to the cloud or CDN provider they belong
+// to. Ranges are stored as tab separated prefix and provider lines.
+type ProviderRanges struct {
+	// prefixes holds each provider's aggregated, sorted prefixes.
+	prefixes map[string][]netip.Prefix
+}
+
+// BundledProviderRanges returns the provider ranges shipped with scopious.
+func BundledProviderRanges() *ProviderRanges {
+	ranges, err := ParseProviderRanges(bytes.NewReader(bundledProviderRanges))
+	if err != nil {
+		panic(err)
+	}
+	return ranges
+}
+
+// LoadProviderRanges reads provider ranges from path, falling back to the
+// bundled ranges when the file does not exist.
+func LoadProviderRanges(path string) (*ProviderRanges, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return BundledProviderRanges(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseProviderRanges(file)
+}
+
+// ParseProviderRanges reads tab separated prefix and provider lines. Blank
+// lines and lines starting with # are skipped.
+func ParseProviderRanges(r io.Reader) (*ProviderRanges, error) {
+	byProvider := map[string][]netip.Prefix{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a prefix and a provider", lineNumber)
+		}
+		prefix, ok := itemPrefix(fields[0])
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid prefix %q", lineNumber, fields[0])
+		}
+		byProvider[fields[1]] = append(byProvider[fields[1]], prefix)
+	}
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+	return newProviderRanges(byProvider), nil
+}
+
+func newProviderRanges(byProvider map[string][]netip.Prefix) *ProviderRanges {
+	ranges := &ProviderRanges{prefixes: map[string][]netip.Prefix{}}
+	for provider, prefixes := range byProvider {
+		ranges.prefixes[provider] = utils.AggregatePrefixes(prefixes)
+	}
+	return ranges
+}
+
+// Providers returns the names of the providers with ranges.
+func (p *ProviderRanges) Providers() []string {
+	return sortedMapKeys(p.prefixes)
+}
+
+// Prefixes returns a provider's aggregated ranges.
+func (p *ProviderRanges) Prefixes(provider string) []netip.Prefix {
+	return p.prefixes[provider]
+}
+
+// Lookup returns the providers whose ranges overlap prefix.
+func (p *ProviderRanges) Lookup(prefix netip.Prefix) []string {
+	prefix = prefix.Masked()
+	providers := []string{}
+	for _, provider := range p.Providers() {
+		prefixes := p.prefixes[provider]
+		// prefixes are sorted and don't overlap, so only the last one
+		// starting at or before prefix and those after it can overlap
+		i := sort.Search(len(prefixes), func(i int) bool {
+			return prefix.Addr().Less(prefixes[i].Addr())
+		})
+		if i > 0 {
+			i--
+		}
+		for ; i < len(prefixes); i++ {
+			if prefixes[i].Overlaps(prefix) {
+				providers = append(providers, provider)
+				break
+			}
+			if utils.LastAddr(prefix).Less(prefixes[i].Addr()) {
+				break
+			}
+		}
+	}
+	return providers
+}
+
+// LookupItem returns the providers of an IP address or CIDR scope item.
+func (p *ProviderRanges) LookupItem(item string) []string {
+	prefix, ok := scopeItemPrefix(item)
+	if !ok {
+		return nil
+	}
+	return p.Lookup(prefix)
+}
+
+// Write writes the ranges in the format read by ParseProviderRanges.
+func (p *ProviderRanges) Write(w io.Writer) error {
+	for _, provider := range p.Providers() {
+		for _, prefix := range p.prefixes[provider] {
+			_, err := fmt.Fprintf(w, "%s\t%s\n", prefix, provider)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ProviderSource is where a provider publishes its address ranges.
+type ProviderSource struct {
+	Provider string
+	URL      string
+	// Parse extracts the prefixes from the published document.
+	Parse func(data []byte) ([]string, error)
+}
+
+// ProviderSources are the published range lists fetched by
+// UpdateProviderRanges.
+var ProviderSources = []ProviderSource{
+	{Provider: "aws", URL: "https://ip-ranges.amazonaws.com/ip-ranges.json", Parse: parseJSONPrefixes("prefixes.ip_prefix", "ipv6_prefixes.ipv6_prefix")},
+	{Provider: "gcp", URL: "https://www.gstatic.com/ipranges/cloud.json", Parse: parseJSONPrefixes("prefixes.ipv4Prefix", "prefixes.ipv6Prefix")},
+	{Provider: "cloudflare", URL: "https://www.cloudflare.com/ips-v4", Parse: parseLinePrefixes},
+	{Provider: "cloudflare", URL: "https://www.cloudflare.com/ips-v6", Parse: parseLinePrefixes},
+	{Provider: "fastly", URL: "https://api.fastly.com/public-ip-list", Parse: parseJSONPrefixes("addresses", "ipv6_addresses")},
+	{Provider: "digitalocean", URL: "https://digitalocean.com/geo/google.csv", Parse: parseLinePrefixes},
+}
+
+// UpdateProviderRanges downloads the current ranges from sources.
+func UpdateProviderRanges(ctx context.Context, client *http.Client, sources []ProviderSource) (*ProviderRanges, error) {
+	byProvider := map[string][]netip.Prefix{}
+	for _, source := range sources {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", source.URL, response.Status)
+		}
+
+		items, err := source.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.URL, err)
+		}
+		for _, item := range items {
+			prefix, ok := itemPrefix(item)
+			if ok {
+				byProvider[source.Provider] = append(byProvider[source.Provider], prefix)
+			}
+		}
+	}
+	return newProviderRanges(byProvider), nil
+}
+
+// parseLinePrefixes reads a prefix from the start of each line, which also
+// covers CSV files with the prefix in the first column.
+func parseLinePrefixes(data []byte) ([]string, error) {
+	items := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		item, _, _ := strings.Cut(strings.TrimSpace(line), ",")
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// parseJSONPrefixes reads prefixes found at dotted paths in a JSON document.
+// Arrays along the path are walked, so "prefixes.ip_prefix" reads ip_prefix
+// from every object in the prefixes array.
+func parseJSONPrefixes(paths ...string) func(data []byte) ([]string, error) {
+	return func(data []byte) ([]string, error) {
+		var document any
+		err := json.Unmarshal(data, &document)
+		if err != nil {
+			return nil, err
+		}
+
+		items := []string{}
+		for _, path := range paths {
+			items = append(items, jsonStrings(document, strings.Split(path, "."))...)
+		}
+		return items, nil
+	}
+}
+
+func jsonStrings(value any, path []string) []string {
+	switch typed := value.(type) {
+	case []any:
+		items := []string{}
+		for _, element := range typed {
+			items = append(items, jsonStrings(element, path)...)
+		}
+		return items
+	case map[string]any:
+		if len(path) == 0 {
+			return nil
+		}
+		return jsonStrings(typed[path[0]], path[1:])
+	case string:
+		if len(path) == 0 {
+			return []string{typed}
+		}
+	}
+	return nil
+}
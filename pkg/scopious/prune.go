@@ -0,0 +1,226 @@
+package scopious
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+)
+
+// Ways PruneStream can drop repeated lines.
+const (
+	PruneDedupNone  = "none"
+	PruneDedupExact = "exact"
+	PruneDedupBloom = "bloom"
+)
+
+// DefaultBloomSize is the memory used to drop repeated lines with
+// PruneDedupBloom, enough for about 50 million lines.
+const DefaultBloomSize = 64 << 20
+
+// pruneBatchSize is the most lines handed to a worker at once.
+const pruneBatchSize = 1024
+
+// PruneOptions configures PruneStream.
+type PruneOptions struct {
+	// Dedup is how repeated lines are dropped, PruneDedupExact when empty.
+	// Exact remembers every line kept. Bloom uses BloomSize bytes however
+	// long the input is, but will drop the odd line it hasn't seen before.
+	Dedup     string
+	BloomSize int
+	// Workers is how many lines are checked at once, the number of CPUs when
+	// zero. Lines are written in the order they were read.
+	Workers int
+	// Explain decides which lines are in scope instead of the scope's
+	// Matcher. It is called from several goroutines at once.
+	Explain func(line string) Explanation
+	// Invert writes the lines that are out of scope instead.
+	Invert bool
+	// Reasons follows each line written with a tab and its verdict.
+	Reasons bool
+	// Summary, when not nil, counts every line read by the reason for its
+	// verdict.
+	Summary map[string]int
+}
+
+type pruneBatch struct {
+	lines    []string
+	verdicts []Explanation
+	done     chan struct{}
+}
+
+// PruneStream copies the lines of r that are in scope to w, or those that
+// aren't when options.Invert is set. Lines are
+// checked in parallel and written as soon as they are, so input that never
+// ends, such as tail -f, can be pruned. It returns when r is exhausted or ctx
+// is cancelled.
+func (s *Scope) PruneStream(ctx context.Context, r io.Reader, w io.Writer, options PruneOptions) error {
+	firstSeen, err := pruneDedup(options)
+	if err != nil {
+		return err
+	}
+	explain := options.Explain
+	if explain == nil {
+		explain = s.Compile().Explain
+	}
+	workers := options.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan *pruneBatch)
+	// batches are written in the order they were read, this also limits how
+	// far ahead of the writer the reader can get
+	ordered := make(chan *pruneBatch, workers*2)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for batch := range jobs {
+				for i, line := range batch.lines {
+					batch.verdicts[i] = explain(line)
+				}
+				close(batch.done)
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		readErr <- readPruneBatches(ctx, r, jobs, ordered)
+	}()
+
+	output := bufio.NewWriter(w)
+	for {
+		var batch *pruneBatch
+		var ok bool
+		select {
+		case batch, ok = <-ordered:
+		case <-ctx.Done():
+			// the reader may be blocked reading input that never comes
+			return ctx.Err()
+		}
+		if !ok {
+			break
+		}
+		select {
+		case <-batch.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		for i, line := range batch.lines {
+			verdict := batch.verdicts[i]
+			if options.Summary != nil {
+				options.Summary[verdict.Reason]++
+			}
+			if verdict.InScope == options.Invert || !firstSeen(line) {
+				continue
+			}
+			output.WriteString(line)
+			if options.Reasons {
+				output.WriteByte('\t')
+				output.WriteString(verdict.String())
+			}
+			output.WriteByte('\n')
+		}
+		if len(ordered) == 0 {
+			// nothing else is ready, don't hold back what we have
+			err = output.Flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = output.Flush()
+	if err != nil {
+		return err
+	}
+	return <-readErr
+}
+
+// readPruneBatches splits r into batches of lines. A batch is sent early when
+// no more input is buffered, so slow input isn't held up waiting for a full
+// batch.
+func readPruneBatches(ctx context.Context, r io.Reader, jobs chan<- *pruneBatch, ordered chan<- *pruneBatch) error {
+	reader := bufio.NewReaderSize(r, 64<<10)
+	lines := []string{}
+
+	send := func() error {
+		batch := &pruneBatch{
+			lines:    lines,
+			verdicts: make([]Explanation, len(lines)),
+			done:     make(chan struct{}),
+		}
+		lines = []string{}
+		for _, ch := range []chan<- *pruneBatch{jobs, ordered} {
+			select {
+			case ch <- batch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			lines = append(lines, line)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if len(lines) > 0 && (err != nil || len(lines) == pruneBatchSize || reader.Buffered() == 0) {
+			sendErr := send()
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// pruneDedup returns a func reporting whether a line is being seen for the
+// first time.
+func pruneDedup(options PruneOptions) (func(line string) bool, error) {
+	switch options.Dedup {
+	case PruneDedupNone:
+		return func(string) bool {
+			return true
+		}, nil
+	case "", PruneDedupExact:
+		seen := map[string]bool{}
+		return func(line string) bool {
+			if seen[line] {
+				return false
+			}
+			seen[line] = true
+			return true
+		}, nil
+	case PruneDedupBloom:
+		size := options.BloomSize
+		if size <= 0 {
+			size = DefaultBloomSize
+		}
+		filter := utils.NewBloomFilter(size)
+		return func(line string) bool {
+			return !filter.Add(line)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown dedup mode %q, expected one of %s, %s or %s", options.Dedup, PruneDedupNone, PruneDedupExact, PruneDedupBloom)
+	}
+}
@@ -0,0 +1,132 @@
+package scopious
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScope_PruneStream(t *testing.T) {
+	scope := inputTestScope()
+	input := []string{}
+	want := []string{}
+	for i := 0; i < 5000; i++ {
+		input = append(input, fmt.Sprintf("https://host%d.inscope.tld/", i%3000), fmt.Sprintf("http://host%d.example.com/", i))
+		if i < 3000 {
+			want = append(want, fmt.Sprintf("https://host%d.inscope.tld/", i))
+		}
+	}
+	input = append(input, "notinscope.inscope.tld", "10.42.0.1", "10.42.0.2\r", "10.42.2.42")
+	want = append(want, "10.42.0.2", "10.42.2.42")
+
+	tests := []struct {
+		dedup    string
+		wantLen  int
+		wantErr  bool
+		sameHead bool
+	}{
+		{dedup: "", wantLen: len(want)},
+		{dedup: PruneDedupExact, wantLen: len(want)},
+		{dedup: PruneDedupBloom, wantLen: len(want)},
+		{dedup: PruneDedupNone, wantLen: len(want) + 2000},
+		{dedup: "sometimes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dedup, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			err := scope.PruneStream(context.Background(), strings.NewReader(strings.Join(input, "\n")), output, PruneOptions{
+				Dedup:   tt.dedup,
+				Workers: 4,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PruneStream() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+			if len(got) != tt.wantLen {
+				t.Fatalf("PruneStream() wrote %d lines, want %d", len(got), tt.wantLen)
+			}
+			if tt.dedup == PruneDedupNone {
+				return
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("line %d = %s, want %s", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScope_PruneStream_Invert(t *testing.T) {
+	input := "www.inscope.tld\nnotinscope.inscope.tld\n10.42.0.1\nexample.com\nexample.com\n10.42.0.2\n"
+	output := &bytes.Buffer{}
+	summary := map[string]int{}
+	err := inputTestScope().PruneStream(context.Background(), strings.NewReader(input), output, PruneOptions{
+		Invert:  true,
+		Reasons: true,
+		Summary: summary,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "notinscope.inscope.tld\texcluded: notinscope.inscope.tld\n" +
+		"10.42.0.1\texcluded: 10.42.0.0/31\n" +
+		"example.com\tnot in domain scope\n"
+	if output.String() != want {
+		t.Errorf("PruneStream() wrote\n%s\nwant\n%s", output.String(), want)
+	}
+	wantSummary := map[string]int{
+		"excluded":                        2,
+		"not in domain scope":             2,
+		"included":                        1,
+		"subdomain of an in scope domain": 1,
+	}
+	if !reflect.DeepEqual(summary, wantSummary) {
+		t.Errorf("Summary = %v, want %v", summary, wantSummary)
+	}
+}
+
+func TestScope_PruneStream_Streaming(t *testing.T) {
+	inputReader, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result := make(chan error)
+	go func() {
+		result <- inputTestScope().PruneStream(ctx, inputReader, outputWriter, PruneOptions{
+			Explain: func(line string) Explanation {
+				return Explanation{Item: line, InScope: line != "drop"}
+			},
+		})
+	}()
+
+	output := bufio.NewScanner(outputReader)
+	for _, line := range []string{"drop", "first", "second"} {
+		fmt.Fprintln(inputWriter, line)
+		if line == "drop" {
+			continue
+		}
+		// lines are written before the input ends
+		if !output.Scan() || output.Text() != line {
+			t.Fatalf("read %q, want %s", output.Text(), line)
+		}
+	}
+
+	cancel()
+	err := <-result
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("PruneStream() error = %v, want context.Canceled", err)
+	}
+	inputWriter.Close()
+}
@@ -0,0 +1,161 @@
+package scopious
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/netip"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	ReportFormatMarkdown = "markdown"
+	ReportFormatHTML     = "html"
+)
+
+//go:embed templates
+var reportTemplates embed.FS
+
+// ReportItem is a scope item and its note.
+type ReportItem struct {
+	Item string
+	Note string
+}
+
+// ReportDomainGroup is the in scope domains sharing a root domain.
+type ReportDomainGroup struct {
+	RootDomain string
+	Domains    []ReportItem
+}
+
+// ReportCIDR is an included IP address or CIDR with the number of its
+// addresses still in scope after excludes.
+type ReportCIDR struct {
+	ReportItem
+	Addresses string
+}
+
+// ReportData is what scope report templates are rendered with. Everything is
+// sorted so reports can be diffed between versions.
+type ReportData struct {
+	Name         string
+	Description  string
+	Windows      []string
+	DomainGroups []ReportDomainGroup
+	CIDRs        []ReportCIDR
+	Excludes     []ReportItem
+	Stats        ScopeStats
+}
+
+// ReportData collects what a scope report shows.
+func (s *Scope) ReportData() ReportData {
+	data := ReportData{
+		Name:        filepath.Base(s.path),
+		Description: s.description,
+		Windows:     s.Windows(),
+		Stats:       s.Stats(),
+	}
+
+	// domains listed in scope but excluded are only shown as excludes
+	matcher := s.Compile()
+	groups := map[string][]ReportItem{}
+	for _, domain := range s.AllDomains() {
+		if !matcher.ContainsDomain(strings.TrimPrefix(domain, "*.")) {
+			continue
+		}
+		rootDomain, err := publicsuffix.EffectiveTLDPlusOne(strings.TrimPrefix(domain, "*."))
+		if err != nil {
+			rootDomain = domain
+		}
+		groups[rootDomain] = append(groups[rootDomain], ReportItem{Item: domain, Note: s.notes[domain]})
+	}
+	for _, rootDomain := range sortedMapKeys(groups) {
+		data.DomainGroups = append(data.DomainGroups, ReportDomainGroup{RootDomain: rootDomain, Domains: groups[rootDomain]})
+	}
+
+	effective := s.EffectivePrefixes()
+	prefixes := s.includedItemPrefixes()
+	items := sortedMapKeys(prefixes)
+	sort.SliceStable(items, func(i, j int) bool {
+		return comparePrefixes(prefixes[items[i]], prefixes[items[j]]) < 0
+	})
+	for _, item := range items {
+		inScope := utils.IntersectPrefixes([]netip.Prefix{prefixes[item]}, effective)
+		data.CIDRs = append(data.CIDRs, ReportCIDR{
+			ReportItem: ReportItem{Item: item, Note: s.notes[item]},
+			Addresses:  utils.CountAddresses(inScope).String(),
+		})
+	}
+
+	for _, exclude := range sortedScopeKeys(s.excludes) {
+		data.Excludes = append(data.Excludes, ReportItem{Item: exclude, Note: s.notes[exclude]})
+	}
+	return data
+}
+
+// Report renders the scope as a Markdown or HTML document. An empty
+// templateText uses the built in template for the format.
+func (s *Scope) Report(w io.Writer, format string, templateText string) error {
+	name := ""
+	switch format {
+	case ReportFormatMarkdown:
+		name = "templates/report.md.tmpl"
+	case ReportFormatHTML:
+		name = "templates/report.html.tmpl"
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+
+	if templateText == "" {
+		builtIn, err := reportTemplates.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		templateText = string(builtIn)
+	}
+
+	data := s.ReportData()
+	if format == ReportFormatHTML {
+		tmpl, err := htmltemplate.New("report").Parse(templateText)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(w, data)
+	}
+
+	tmpl, err := texttemplate.New("report").Funcs(texttemplate.FuncMap{
+		"cell": markdownCell,
+	}).Parse(templateText)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
+
+// markdownCell escapes text for use in a Markdown table cell.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+func comparePrefixes(a netip.Prefix, b netip.Prefix) int {
+	if a.Addr() != b.Addr() {
+		return a.Addr().Compare(b.Addr())
+	}
+	return a.Bits() - b.Bits()
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,55 @@
+package scopious
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScope_Report(t *testing.T) {
+	scoper := openScoper(t, t.TempDir())
+	s := scoper.GetScope("acme")
+	s.SetDescription("External infrastructure for Acme.")
+	s.SetWindows([]string{"2026-10-20 to 2026-10-24, 09:00-17:00 UTC"})
+	s.Add("www.example.com", "example.com", "api.example.org", "203.0.113.0/24", "192.0.2.10")
+	s.AddExclude("203.0.113.128/25", "admin.example.com", "api.example.org")
+	s.SetNote("192.0.2.10", "mail | relay")
+	s.Save()
+
+	// description and windows survive a reload
+	s = openScoper(t, scoper.Dir()).GetScope("acme")
+
+	var markdown bytes.Buffer
+	err := s.Report(&markdown, ReportFormatMarkdown, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "report", "acme.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markdown.String() != string(want) {
+		t.Errorf("Report(markdown) =\n%s\nwant\n%s", markdown.String(), want)
+	}
+
+	var html bytes.Buffer
+	err = s.Report(&html, ReportFormatHTML, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "<td>192.0.2.10</td><td>1</td><td>mail | relay</td>") {
+		t.Errorf("Report(html) =\n%s", html.String())
+	}
+
+	var custom bytes.Buffer
+	err = s.Report(&custom, ReportFormatMarkdown, "{{ .Name }}: {{ len .CIDRs }} CIDRs")
+	if err != nil || custom.String() != "acme: 2 CIDRs" {
+		t.Errorf("Report(custom) = %q, %v", custom.String(), err)
+	}
+
+	if s.Report(&custom, "pdf", "") == nil {
+		t.Errorf("Report(pdf) error = nil")
+	}
+}
@@ -0,0 +1,220 @@
+package scopious
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ResolutionInScope    = "in-scope"
+	ResolutionExcluded   = "excluded"
+	ResolutionOutOfScope = "out-of-scope"
+	ResolutionUnresolved = "unresolved"
+)
+
+// Resolution is the result of looking up a domain.
+type Resolution struct {
+	Domain     string    `json:"domain"`
+	Addrs      []string  `json:"addrs"`
+	Error      string    `json:"error,omitempty"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// ResolutionCache holds resolutions by domain so later runs can work offline.
+type ResolutionCache struct {
+	Path        string                `json:"-"`
+	Resolutions map[string]Resolution `json:"resolutions"`
+	mutex       sync.Mutex
+}
+
+// LoadResolutionCache reads a resolution cache. A missing file is an empty cache.
+func LoadResolutionCache(path string) (*ResolutionCache, error) {
+	cache := &ResolutionCache{
+		Path:        path,
+		Resolutions: map[string]Resolution{},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, cache)
+	if err != nil {
+		return nil, err
+	}
+	if cache.Resolutions == nil {
+		cache.Resolutions = map[string]Resolution{}
+	}
+	return cache, nil
+}
+
+// Get returns the cached resolution for domain.
+func (c *ResolutionCache) Get(domain string) (Resolution, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	resolution, ok := c.Resolutions[domain]
+	return resolution, ok
+}
+
+// Set caches the resolution for its domain.
+func (c *ResolutionCache) Set(resolution Resolution) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Resolutions[resolution.Domain] = resolution
+}
+
+// Save writes the cache to its path.
+func (c *ResolutionCache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, data, 0644)
+}
+
+// DomainResolver looks up domains, through the cache when one is set. Offline
+// resolvers only ever use the cache.
+type DomainResolver struct {
+	Resolver *net.Resolver
+	Cache    *ResolutionCache
+	Offline  bool
+	Timeout  time.Duration
+}
+
+// NewDomainResolver creates a resolver that sends queries to address, like
+// 127.0.0.1:53. The system resolver is used when address is empty.
+func NewDomainResolver(address string) *DomainResolver {
+	resolver := net.DefaultResolver
+	if address != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialer := net.Dialer{}
+				return dialer.DialContext(ctx, network, address)
+			},
+		}
+	}
+
+	return &DomainResolver{
+		Resolver: resolver,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// Resolve looks up the addresses of domain. Lookup failures are recorded in
+// the resolution rather than returned, so they can be cached as well.
+func (r *DomainResolver) Resolve(ctx context.Context, domain string) Resolution {
+	if r.Cache != nil {
+		resolution, ok := r.Cache.Get(domain)
+		if ok {
+			return resolution
+		}
+	}
+
+	if r.Offline {
+		return Resolution{Domain: domain, Addrs: []string{}, Error: "not in resolution cache"}
+	}
+
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	resolution := Resolution{
+		Domain:     domain,
+		Addrs:      []string{},
+		ResolvedAt: time.Now().UTC(),
+	}
+	addrs, err := r.Resolver.LookupIPAddr(ctx, domain)
+	if err != nil {
+		resolution.Error = err.Error()
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && (dnsErr.IsTimeout || dnsErr.IsTemporary) {
+			// don't cache failures that may go away
+			return resolution
+		}
+	}
+	for _, addr := range addrs {
+		resolution.Addrs = append(resolution.Addrs, addr.IP.String())
+	}
+	sort.Strings(resolution.Addrs)
+
+	if r.Cache != nil {
+		r.Cache.Set(resolution)
+	}
+	return resolution
+}
+
+// ClassifyResolution is Matcher.ClassifyResolution for the scope.
+func (s *Scope) ClassifyResolution(resolution Resolution) string {
+	return s.Compile().ClassifyResolution(resolution)
+}
+
+// IsResolvedInScope is Matcher.IsResolvedInScope for the scope.
+func (s *Scope) IsResolvedInScope(ctx context.Context, resolver *DomainResolver, item string) bool {
+	return s.Compile().IsResolvedInScope(ctx, resolver, item)
+}
+
+// ClassifyResolution reports whether the addresses a domain resolved to are
+// all in IP scope. Excluded addresses take precedence over out of scope ones.
+func (m *Matcher) ClassifyResolution(resolution Resolution) string {
+	if len(resolution.Addrs) == 0 {
+		return ResolutionUnresolved
+	}
+
+	status := ResolutionInScope
+	for _, addr := range resolution.Addrs {
+		ip, err := netip.ParseAddr(addr)
+		if err != nil || m.ExcludesAddr(ip) {
+			return ResolutionExcluded
+		}
+		if !m.ContainsAddr(ip) {
+			status = ResolutionOutOfScope
+		}
+	}
+	return status
+}
+
+// IsResolvedInScope reports whether item is in scope and, when it is a domain,
+// whether it resolves only to in scope addresses.
+func (m *Matcher) IsResolvedInScope(ctx context.Context, resolver *DomainResolver, item string) bool {
+	return m.ExplainResolved(ctx, resolver, item).InScope
+}
+
+// ExplainResolved explains item like Explain, but in scope domains are also
+// resolved and only stay in scope when they resolve to in scope addresses.
+func (m *Matcher) ExplainResolved(ctx context.Context, resolver *DomainResolver, item string) Explanation {
+	explanation := m.Explain(item)
+	if !explanation.InScope || explanation.Kind != ExplainKindDomain {
+		return explanation
+	}
+
+	explanation.InScope = false
+	explanation.Rule = ""
+	switch m.ClassifyResolution(resolver.Resolve(ctx, explanation.Normalized)) {
+	case ResolutionInScope:
+		explanation.InScope = true
+	case ResolutionExcluded:
+		explanation.Reason = "resolves to an excluded address"
+	case ResolutionOutOfScope:
+		explanation.Reason = "resolves outside IP scope"
+	default:
+		explanation.Reason = "does not resolve"
+	}
+	return explanation
+}
@@ -0,0 +1,128 @@
+package scopious
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startStubDNS answers A queries from records and NXDOMAIN for anything else.
+func startStubDNS(t *testing.T, records map[string][]string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var request dnsmessage.Message
+			if request.Unpack(buf[:n]) != nil || len(request.Questions) == 0 {
+				continue
+			}
+			question := request.Questions[0]
+
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: request.ID, Response: true, Authoritative: true},
+				Questions: request.Questions,
+			}
+			addrs, ok := records[question.Name.String()]
+			if !ok {
+				response.RCode = dnsmessage.RCodeNameError
+			}
+			if question.Type == dnsmessage.TypeA {
+				for _, addr := range addrs {
+					a := dnsmessage.AResource{}
+					copy(a.A[:], net.ParseIP(addr).To4())
+					response.Answers = append(response.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &a,
+					})
+				}
+			}
+
+			packed, err := response.Pack()
+			if err == nil {
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestScope_ClassifyResolution(t *testing.T) {
+	address := startStubDNS(t, map[string][]string{
+		"www.example.com.":     {"203.0.113.10"},
+		"cdn.example.com.":     {"203.0.113.11", "192.0.2.80"},
+		"billing.example.com.": {"203.0.113.129"},
+	})
+
+	s := NewScopeFromPath("")
+	s.AddExclude("203.0.113.128/25")
+	s.Add("example.com", "203.0.113.0/24")
+
+	resolver := NewDomainResolver(address)
+	cachePath := filepath.Join(t.TempDir(), "resolved.json")
+	resolver.Cache, _ = LoadResolutionCache(cachePath)
+
+	tests := map[string]string{
+		"www.example.com":     ResolutionInScope,
+		"cdn.example.com":     ResolutionOutOfScope,
+		"billing.example.com": ResolutionExcluded,
+		"gone.example.com":    ResolutionUnresolved,
+	}
+	for domain, want := range tests {
+		t.Run(domain, func(t *testing.T) {
+			resolution := resolver.Resolve(context.Background(), domain)
+			if got := s.ClassifyResolution(resolution); got != want {
+				t.Errorf("ClassifyResolution(%s) = %v, want %v (%v)", domain, got, want, resolution)
+			}
+			if got := s.Compile().ClassifyResolution(resolution); got != want {
+				t.Errorf("Matcher.ClassifyResolution(%s) = %v, want %v (%v)", domain, got, want, resolution)
+			}
+		})
+	}
+
+	err := resolver.Cache.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offline := NewDomainResolver("127.0.0.1:1")
+	offline.Offline = true
+	offline.Cache, err = LoadResolutionCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolution := offline.Resolve(context.Background(), "cdn.example.com")
+	if !reflect.DeepEqual(resolution.Addrs, []string{"192.0.2.80", "203.0.113.11"}) {
+		t.Errorf("cached Addrs = %v", resolution.Addrs)
+	}
+
+	if !s.IsResolvedInScope(context.Background(), offline, "www.example.com") {
+		t.Errorf("IsResolvedInScope(www.example.com) = false, want true")
+	}
+	if s.IsResolvedInScope(context.Background(), offline, "cdn.example.com") {
+		t.Errorf("IsResolvedInScope(cdn.example.com) = true, want false")
+	}
+	if !s.IsResolvedInScope(context.Background(), offline, "203.0.113.20") {
+		t.Errorf("IsResolvedInScope(203.0.113.20) = false, want true")
+	}
+
+	matcher := s.Compile()
+	for item, want := range map[string]bool{"www.example.com": true, "cdn.example.com": false, "203.0.113.20": true, "203.0.113.130": false} {
+		if got := matcher.IsResolvedInScope(context.Background(), offline, item); got != want {
+			t.Errorf("Matcher.IsResolvedInScope(%s) = %v, want %v", item, got, want)
+		}
+	}
+}
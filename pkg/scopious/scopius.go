@@ -1,10 +1,16 @@
+// Package scopious manages the scope of network engagements: IP addresses,
+// CIDRs and domains that may be tested, and the exclusions carved out of them.
+// Open a scope directory to load and edit scopes, then Compile a scope into a
+// Matcher to check items against it.
 package scopious
 
 import (
+	"errors"
 	"fmt"
 	"github.com/analog-substance/util/fileutil"
 	"log"
 	"net"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -23,102 +29,166 @@ const scopeFileIPv4 = "ipv4.txt"
 const scopeFileIPv6 = "ipv6.txt"
 const scopeFileDomains = "domains.txt"
 const scopeFileExclude = "exclude.txt"
+const scopeFileNotes = "notes.tsv"
+const scopeFileDescription = "description.txt"
+const scopeFileWindows = "windows.txt"
+const scopeFilePending = "pending.tsv"
 
 var ipv6Regexp = regexp.MustCompile("([0-9a-f]{4}::?)+([0-9a-f]{4})")
 
+// Scoper holds the scopes stored in a scope directory, one subdirectory per
+// scope.
 type Scoper struct {
-	Scopes   map[string]*Scope
-	ScopeDir string
+	scopes       map[string]*Scope
+	dir          string
+	create       bool
+	defaultScope string
 }
 
-func New() *Scoper {
-	return FromPath(DefaultScopeDir)
+// Option configures Open.
+type Option func(*Scoper)
+
+// WithCreate creates the scope directory when it doesn't exist, along with the
+// default scope when there are no scopes yet.
+func WithCreate() Option {
+	return func(scoper *Scoper) {
+		scoper.create = true
+	}
+}
+
+// WithDefaultScope sets the scope WithCreate creates in an empty scope
+// directory, DefaultScope unless set.
+func WithDefaultScope(name string) Option {
+	return func(scoper *Scoper) {
+		scoper.defaultScope = name
+	}
 }
 
-func FromPath(scoperPath string) *Scoper {
-	s := &Scoper{
-		ScopeDir: scoperPath,
-		Scopes:   map[string]*Scope{},
+// Open loads the scopes stored in dir.
+func Open(dir string, options ...Option) (*Scoper, error) {
+	scoper := &Scoper{
+		scopes:       map[string]*Scope{},
+		dir:          dir,
+		defaultScope: DefaultScope,
+	}
+	for _, option := range options {
+		option(scoper)
 	}
 
-	s.Load()
-	return s
+	err := scoper.Load()
+	if err != nil {
+		return nil, err
+	}
+	return scoper, nil
 }
 
-func (scoper *Scoper) Load() {
-	dirs, err := os.ReadDir(scoper.ScopeDir)
+// Load reads every scope in the scope directory.
+func (scoper *Scoper) Load() error {
+	dirs, err := os.ReadDir(scoper.dir)
+	if errors.Is(err, os.ErrNotExist) && scoper.create {
+		err = os.MkdirAll(scoper.dir, 0755)
+	}
 	if err != nil {
-		err = os.Mkdir(scoper.ScopeDir, 0755)
-		if err != nil {
-			panic(err)
-		}
+		return err
 	}
 
 	for _, dirEntry := range dirs {
 		if dirEntry.IsDir() {
 			scopeName := dirEntry.Name()
-			scoper.Scopes[scopeName] = NewScopeFromPath(filepath.Join(scoper.ScopeDir, scopeName))
-			scoper.Scopes[scopeName].Load()
+			scope := NewScopeFromPath(filepath.Join(scoper.dir, scopeName))
+			err = scope.Load()
+			if err != nil {
+				return fmt.Errorf("scope %s: %w", scopeName, err)
+			}
+			scoper.scopes[scopeName] = scope
 		}
 	}
 
-	if len(scoper.Scopes) == 0 {
-		// maybe error instead
-		scoper.GetScope(DefaultScope)
+	if len(scoper.scopes) == 0 && scoper.create {
+		return os.MkdirAll(scoper.GetScope(scoper.defaultScope).path, 0755)
 	}
+	return nil
 }
-func (scoper *Scoper) Save() {
-	for _, scope := range scoper.Scopes {
-		scope.Save()
+
+// Save writes every scope to the scope directory.
+func (scoper *Scoper) Save() error {
+	errs := []error{}
+	for _, name := range scoper.Names() {
+		errs = append(errs, scoper.scopes[name].Save())
 	}
+	return errors.Join(errs...)
+}
+
+// Dir returns the scope directory.
+func (scoper *Scoper) Dir() string {
+	return scoper.dir
 }
 
+// Names returns the names of the scopes, sorted.
+func (scoper *Scoper) Names() []string {
+	return sortedMapKeys(scoper.scopes)
+}
+
+// Lookup returns the named scope if it exists.
+func (scoper *Scoper) Lookup(scopeName string) (*Scope, bool) {
+	scope, exists := scoper.scopes[scopeName]
+	return scope, exists
+}
+
+// GetScope returns the named scope, adding an empty one when it doesn't exist.
+// New scopes are written to disk when saved.
 func (scoper *Scoper) GetScope(scopeName string) *Scope {
-	scope, exists := scoper.Scopes[scopeName]
+	scope, exists := scoper.scopes[scopeName]
 	if exists {
 		return scope
 	}
 
-	scopeFile := scoper.GetScopePath(scopeName)
-	err := os.Mkdir(scopeFile, 0755)
-	if err != nil {
-		panic(err)
-	}
-
-	scoper.Scopes[scopeName] = NewScopeFromPath(scopeFile)
-	return scoper.Scopes[scopeName]
+	scoper.scopes[scopeName] = NewScopeFromPath(scoper.GetScopePath(scopeName))
+	return scoper.scopes[scopeName]
 }
 
 func (scoper *Scoper) GetScopePath(scopeName string) string {
-	return filepath.Join(scoper.ScopeDir, scopeName)
+	return filepath.Join(scoper.dir, scopeName)
 }
 
 func (scoper *Scoper) GetScopeExcludePath(scopeName string) string {
-	return filepath.Join(scoper.ScopeDir, scopeName, scopeFileExclude)
+	return filepath.Join(scoper.dir, scopeName, scopeFileExclude)
+}
+
+func (scoper *Scoper) GetScopeNotesPath(scopeName string) string {
+	return filepath.Join(scoper.dir, scopeName, scopeFileNotes)
+}
+
+func (scoper *Scoper) GetScopeDescriptionPath(scopeName string) string {
+	return filepath.Join(scoper.dir, scopeName, scopeFileDescription)
+}
+
+func (scoper *Scoper) GetScopeWindowsPath(scopeName string) string {
+	return filepath.Join(scoper.dir, scopeName, scopeFileWindows)
 }
 
 func (scoper *Scoper) GetScopeIPv4Path(scopeName string) string {
-	return filepath.Join(scoper.ScopeDir, scopeName, scopeFileIPv4)
+	return filepath.Join(scoper.dir, scopeName, scopeFileIPv4)
 }
 
 func (scoper *Scoper) GetScopeIPv6Path(scopeName string) string {
-	return filepath.Join(scoper.ScopeDir, scopeName, scopeFileIPv6)
+	return filepath.Join(scoper.dir, scopeName, scopeFileIPv6)
 }
 
 func (scoper *Scoper) GetScopeDomainsPath(scopeName string) string {
-	return filepath.Join(scoper.ScopeDir, scopeName, scopeFileDomains)
+	return filepath.Join(scoper.dir, scopeName, scopeFileDomains)
 }
 
 type Scope struct {
-	Path              string
-	Description       string
-	IPv4              map[string]bool
-	Domains           map[string]bool
-	IPv6              map[string]bool
-	Excludes          map[string]bool
-	inScopeCIDRs      map[string]*net.IPNet
-	excludedCIDRs     map[string]*net.IPNet
-	excludedIPAddrs   map[string]bool
+	path              string
+	description       string
+	ipv4              map[string]bool
+	domains           map[string]bool
+	ipv6              map[string]bool
+	excludes          map[string]bool
+	notes             map[string]string
+	pending           map[string]Candidate
+	windows           []string
 	excludedHostnames map[string]bool
 	rootDomainMap     map[string]bool
 	rootDomainSorted  []string
@@ -126,83 +196,103 @@ type Scope struct {
 
 func NewScopeFromPath(path string) *Scope {
 	return &Scope{
-		Path:     path,
-		IPv4:     map[string]bool{},
-		IPv6:     map[string]bool{},
-		Domains:  map[string]bool{},
-		Excludes: map[string]bool{},
+		path:     path,
+		ipv4:     map[string]bool{},
+		ipv6:     map[string]bool{},
+		domains:  map[string]bool{},
+		excludes: map[string]bool{},
+		notes:    map[string]string{},
+		pending:  map[string]Candidate{},
 
 		rootDomainMap:    map[string]bool{},
 		rootDomainSorted: []string{},
 	}
 }
 
-func (s *Scope) Load() {
-	dirs, err := os.ReadDir(s.Path)
+// Load reads the scope files, missing files are left empty.
+func (s *Scope) Load() error {
+	dirs, err := os.ReadDir(s.path)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	for _, dirEntry := range dirs {
-		if !dirEntry.IsDir() {
-			if dirEntry.Name() == scopeFileIPv4 {
-				s.IPv4, err = fileutil.ReadLowerLineMap(filepath.Join(s.Path, scopeFileIPv4))
-				if err != nil {
-					panic(err)
-				}
-			}
-
-			if dirEntry.Name() == scopeFileIPv6 {
-				s.IPv6, err = fileutil.ReadLowerLineMap(filepath.Join(s.Path, scopeFileIPv6))
-				if err != nil {
-					panic(err)
-				}
-			}
-
-			if dirEntry.Name() == scopeFileDomains {
-				s.Domains, err = fileutil.ReadLowerLineMap(filepath.Join(s.Path, scopeFileDomains))
-				if err != nil {
-					panic(err)
-				}
-			}
-
-			if dirEntry.Name() == scopeFileExclude {
-				s.Excludes, err = fileutil.ReadLowerLineMap(filepath.Join(s.Path, scopeFileExclude))
-				if err != nil {
-					panic(err)
-				}
-			}
+		if dirEntry.IsDir() {
+			continue
+		}
+		path := filepath.Join(s.path, dirEntry.Name())
+
+		switch dirEntry.Name() {
+		case scopeFileIPv4:
+			s.ipv4, err = fileutil.ReadLowerLineMap(path)
+		case scopeFileIPv6:
+			s.ipv6, err = fileutil.ReadLowerLineMap(path)
+		case scopeFileDomains:
+			s.domains, err = fileutil.ReadLowerLineMap(path)
+		case scopeFileExclude:
+			s.excludes, err = fileutil.ReadLowerLineMap(path)
+		case scopeFileNotes:
+			s.notes, err = readNotes(path)
+		case scopeFileDescription:
+			var description []byte
+			description, err = os.ReadFile(path)
+			s.description = strings.TrimSpace(string(description))
+		case scopeFileWindows:
+			s.windows, err = readWindows(path)
+		case scopeFilePending:
+			s.pending, err = readPending(path)
+		}
+		if err != nil {
+			return err
 		}
 	}
 
 	s.populateExcludes()
-	s.populateIncludes()
+	return nil
 }
 
-func (s *Scope) Save() {
-	err := fileutil.WriteLowerUniqueLines(filepath.Join(s.Path, scopeFileIPv4), sortedScopeKeys(s.IPv4))
-	if err != nil && state.Debug {
-		log.Println("error saving IPv4:", err)
+// Save writes the scope files, creating the scope directory when needed.
+func (s *Scope) Save() error {
+	err := os.MkdirAll(s.path, 0755)
+	if err != nil {
+		return err
 	}
 
-	err = fileutil.WriteLowerUniqueLines(filepath.Join(s.Path, scopeFileIPv6), sortedScopeKeys(s.IPv6))
-	if err != nil && state.Debug {
-		log.Println("error saving IPv6:", err)
+	errs := []error{
+		fileutil.WriteLowerUniqueLines(filepath.Join(s.path, scopeFileIPv4), sortedScopeKeys(s.ipv4)),
+		fileutil.WriteLowerUniqueLines(filepath.Join(s.path, scopeFileIPv6), sortedScopeKeys(s.ipv6)),
+		fileutil.WriteLowerUniqueLines(filepath.Join(s.path, scopeFileDomains), sortedScopeKeys(s.domains)),
+		fileutil.WriteLowerUniqueLines(filepath.Join(s.path, scopeFileExclude), sortedScopeKeys(s.excludes)),
 	}
 
-	err = fileutil.WriteLowerUniqueLines(filepath.Join(s.Path, scopeFileDomains), sortedScopeKeys(s.Domains))
-	if err != nil && state.Debug {
-		log.Println("error saving Domains:", err)
+	// optional files are removed once empty, so cleared values stay cleared
+	if len(s.notes) > 0 {
+		errs = append(errs, writeNotes(filepath.Join(s.path, scopeFileNotes), s.notes))
+	} else {
+		errs = append(errs, removeScopeFile(filepath.Join(s.path, scopeFileNotes)))
 	}
 
-	err = fileutil.WriteLowerUniqueLines(filepath.Join(s.Path, scopeFileExclude), sortedScopeKeys(s.Excludes))
-	if err != nil && state.Debug {
-		log.Println("error saving Excludes:", err)
+	if s.description != "" {
+		errs = append(errs, os.WriteFile(filepath.Join(s.path, scopeFileDescription), []byte(s.description+"\n"), 0644))
+	} else {
+		errs = append(errs, removeScopeFile(filepath.Join(s.path, scopeFileDescription)))
 	}
-}
 
-func (s *Scope) Add(all bool, scopeItems ...string) {
+	if len(s.windows) > 0 {
+		errs = append(errs, os.WriteFile(filepath.Join(s.path, scopeFileWindows), []byte(strings.Join(s.windows, "\n")+"\n"), 0644))
+	} else {
+		errs = append(errs, removeScopeFile(filepath.Join(s.path, scopeFileWindows)))
+	}
+
+	errs = append(errs, writePending(filepath.Join(s.path, scopeFilePending), s.pending))
+	return errors.Join(errs...)
+}
 
+// Add adds IP addresses, CIDRs and domains to the scope, URLs are added by
+// their hostname. Items that have been excluded are skipped.
+func (s *Scope) Add(scopeItems ...string) {
+	// excludes don't change while adding, so they are compiled once
+	var excludes *Matcher
 	for _, scopeItem := range scopeItems {
 		scopeItem = normalizedScope(scopeItem)
 		if scopeItem == "" {
@@ -210,14 +300,15 @@ func (s *Scope) Add(all bool, scopeItems ...string) {
 		}
 
 		// if we have a direct match in our excludes, do not add the scope item
-		_, exists := s.Excludes[scopeItem]
+		_, exists := s.excludes[scopeItem]
 		if exists {
 			continue
 		}
+		delete(s.pending, scopeItem)
 
 		if strings.Contains(scopeItem, "/") {
 			// perhaps we have a CIDR
-			_, err := utils.GetAllIPs(scopeItem, all)
+			_, _, err := net.ParseCIDR(scopeItem)
 			if err != nil {
 				if state.Debug {
 					log.Println("error processing cidr", err)
@@ -227,34 +318,40 @@ func (s *Scope) Add(all bool, scopeItems ...string) {
 
 			// if we have a `:` then we must have an IPv6 address
 			if strings.Contains(scopeItem, ":") {
-				s.IPv6[scopeItem] = true
+				s.ipv6[scopeItem] = true
 				continue
 			}
 
-			s.IPv4[scopeItem] = true
+			s.ipv4[scopeItem] = true
 			continue
 		}
 
 		// if we have a `:` then we must have an IPv6 address
 		if strings.Contains(scopeItem, ":") {
-			s.IPv6[scopeItem] = true
+			s.ipv6[scopeItem] = true
 			continue
 		}
 
-		ip := net.ParseIP(scopeItem)
-		if ip != nil {
-			if s.CanAddIP(&ip) {
-				s.IPv4[ip.String()] = true
+		if excludes == nil {
+			excludes = s.Compile()
+		}
+		addr, err := netip.ParseAddr(scopeItem)
+		if err == nil {
+			if !excludes.ExcludesAddr(addr) {
+				s.ipv4[addr.String()] = true
 			}
 			// item was an IP address, continue now to prevent useless processing
 			continue
 		}
 
 		// not IPv6 or IPv4... must be a domain
-		if s.CanAddDomain(scopeItem) {
-			s.Domains[scopeItem] = true
+		if !excludes.ExcludesDomain(scopeItem) {
+			s.domains[scopeItem] = true
 		}
 	}
+
+	s.rootDomainMap = map[string]bool{}
+	s.rootDomainSorted = []string{}
 }
 
 func (s *Scope) AddExclude(scopeItems ...string) {
@@ -265,95 +362,101 @@ func (s *Scope) AddExclude(scopeItems ...string) {
 			continue
 		}
 
-		s.Excludes[scopeItem] = true
+		s.excludes[scopeItem] = true
+		delete(s.pending, scopeItem)
 	}
+	s.rootDomainMap = map[string]bool{}
+	s.rootDomainSorted = []string{}
+	s.populateExcludes()
 }
 
-func (s *Scope) IsIPInScope(ip *net.IP, mustBeInScope bool) bool {
-	if ip == nil {
-		return false
-	}
-
-	if s.excludedCIDRs == nil {
-		s.populateExcludes()
-	}
-
-	// exclude takes precedence
-	for _, excludeCIDR := range s.excludedCIDRs {
-		if excludeCIDR.Contains(*ip) {
-			return false
+// Remove removes items from the scope. Items are removed as written, removing
+// a domain does not remove its subdomains.
+func (s *Scope) Remove(scopeItems ...string) {
+	for _, scopeItem := range scopeItems {
+		scopeItem = normalizedScope(scopeItem)
+		if scopeItem == "" {
+			continue
 		}
-	}
-	_, ok := s.excludedIPAddrs[ip.String()]
-	if ok {
-		return false
-	}
-
-	if !mustBeInScope {
-		return true
-	}
 
-	_, ok = s.IPv4[ip.String()]
-	if ok {
-		return true
+		delete(s.ipv4, scopeItem)
+		delete(s.ipv6, scopeItem)
+		delete(s.domains, scopeItem)
+		delete(s.notes, scopeItem)
 	}
 
-	if s.inScopeCIDRs == nil {
-		s.populateIncludes()
-	}
+	s.rootDomainMap = map[string]bool{}
+	s.rootDomainSorted = []string{}
+}
 
-	for _, inScopeCIDR := range s.inScopeCIDRs {
-		if inScopeCIDR.Contains(*ip) {
-			return true
+// RemoveExclude removes items from the exclude list.
+func (s *Scope) RemoveExclude(scopeItems ...string) {
+	for _, scopeItem := range scopeItems {
+		scopeItem = normalizedScope(scopeItem)
+		if scopeItem == "" {
+			continue
 		}
+
+		delete(s.excludes, scopeItem)
 	}
-	return false
+
+	s.rootDomainMap = map[string]bool{}
+	s.rootDomainSorted = []string{}
+	s.populateExcludes()
 }
 
-func (s *Scope) getExcludedHostNames() map[string]bool {
+// Path returns the directory the scope is stored in.
+func (s *Scope) Path() string {
+	return s.path
+}
 
-	return s.excludedHostnames
+// Description returns the scope's free form description.
+func (s *Scope) Description() string {
+	return s.description
 }
 
-func (s *Scope) IsDomainInScope(domain string, mustBeInScope bool) bool {
-	if domain == "" {
-		return false
-	}
+// SetDescription replaces the scope's description, an empty description
+// removes it.
+func (s *Scope) SetDescription(description string) {
+	s.description = strings.TrimSpace(description)
+}
 
-	if s.excludedHostnames == nil {
-		s.populateExcludes()
-	}
+// Windows returns a copy of the scope's testing windows.
+func (s *Scope) Windows() []string {
+	return append([]string{}, s.windows...)
+}
 
-	_, ok := s.excludedHostnames[domain]
-	if ok {
-		return false
-	}
-	// is domain implicitly blocked via parent domain
-	for excludedDomain := range s.excludedHostnames {
-		if strings.HasSuffix(domain, "."+excludedDomain) {
-			return false
-		}
-	}
+// SetWindows replaces the scope's testing windows.
+func (s *Scope) SetWindows(windows []string) {
+	s.windows = append([]string{}, windows...)
+}
 
-	if !mustBeInScope {
-		return true
+// SetNote records a note for a scope item, like where it came from or what
+// environment it belongs to. An empty note removes it.
+func (s *Scope) SetNote(scopeItem string, note string) {
+	scopeItem = normalizedScope(scopeItem)
+	if scopeItem == "" {
+		return
 	}
 
-	_, ok = s.Domains[domain]
-	if ok {
-		return true
-	}
-	// is domain implicitly blocked via parent domain
-	for _, includedDomain := range s.RootDomains() {
-		if strings.HasSuffix(domain, "."+includedDomain) {
-			return true
-		}
+	note = strings.Join(strings.Fields(note), " ")
+	if note == "" {
+		delete(s.notes, scopeItem)
+		return
 	}
+	s.notes[scopeItem] = note
+}
 
-	return false
+// ExpandOptions controls how CIDRs are expanded to IP addresses.
+type ExpandOptions struct {
+	// NetworkAndBroadcast keeps the first and last address of each CIDR.
+	NetworkAndBroadcast bool
 }
 
-func (s *Scope) Prune(all bool, scopeItemsToCheck ...string) []string {
+// Prune returns the items that are in scope. CIDRs are expanded to the IP
+// addresses in scope within them.
+func (s *Scope) Prune(scopeItemsToCheck []string, options ExpandOptions) []string {
+	matcher := s.Compile()
 	scopeCheckResults := map[string]bool{}
 
 	for _, scopeToCheck := range scopeItemsToCheck {
@@ -361,15 +464,16 @@ func (s *Scope) Prune(all bool, scopeItemsToCheck ...string) []string {
 		if normalized == "" {
 			continue
 		}
-		ipAddrs, err := utils.GetAllIPs(normalized, all)
+		ipAddrs, err := utils.GetAllIPs(normalized, options.NetworkAndBroadcast)
 		if err == nil {
 			for _, expandedIP := range ipAddrs {
-				if s.IsIPInScope(expandedIP, true) {
+				addr, ok := netip.AddrFromSlice(*expandedIP)
+				if ok && matcher.ContainsAddr(addr) {
 					scopeCheckResults[expandedIP.String()] = true
 				}
 			}
 		} else {
-			if s.IsInScope(normalized) {
+			if matcher.Contains(normalized) {
 				scopeCheckResults[scopeToCheck] = true
 			}
 		}
@@ -382,73 +486,149 @@ func (s *Scope) Prune(all bool, scopeItemsToCheck ...string) []string {
 	return prunedResults
 }
 
+// IsInScope reports whether an IP address, CIDR, URL or domain is in scope.
+// Checking many items is faster with a Matcher from Compile.
 func (s *Scope) IsInScope(itemToCheck string) bool {
-	normalized := normalizedScope(itemToCheck)
-	ip := net.ParseIP(normalized)
-	if ip != nil {
-		return s.IsIPInScope(&ip, true)
-	}
+	return s.Compile().Contains(itemToCheck)
+}
 
-	ips, err := utils.GetAllIPs(normalized, true)
-	if err == nil {
-		for _, ip := range ips {
-			if !s.IsIPInScope(ip, true) {
-				return false
-			}
-		}
-		return true
-	}
+// Expand returns the IP addresses in scope, with CIDRs expanded and excludes
+// removed.
+func (s *Scope) Expand(options ExpandOptions) []string {
+	return s.Prune(s.AllIPs(), options)
+}
 
-	return s.IsDomainInScope(itemToCheck, true)
+// AllIPs returns the IPv4 and IPv6 addresses and CIDRs added to the scope.
+func (s *Scope) AllIPs() []string {
+	return append(s.IPv4(), s.IPv6()...)
 }
 
-func (s *Scope) AllExpanded(all bool) []string {
-	return s.Prune(all, s.AllIPs()...)
+// IPv4 returns the IPv4 addresses and CIDRs added to the scope.
+func (s *Scope) IPv4() []string {
+	return sortedScopeKeys(s.ipv4)
 }
 
-func (s *Scope) AllIPs() []string {
-	return append(sortedScopeKeys(s.IPv4), sortedScopeKeys(s.IPv6)...)
+// IPv6 returns the IPv6 addresses and CIDRs added to the scope.
+func (s *Scope) IPv6() []string {
+	return sortedScopeKeys(s.ipv6)
+}
+
+// Excludes returns the excluded items.
+func (s *Scope) Excludes() []string {
+	return sortedScopeKeys(s.excludes)
+}
+
+// Notes returns a copy of the notes kept for scope items.
+func (s *Scope) Notes() map[string]string {
+	notes := map[string]string{}
+	for item, note := range s.notes {
+		notes[item] = note
+	}
+	return notes
+}
+
+// Note returns the note kept for a scope item.
+func (s *Scope) Note(scopeItem string) string {
+	return s.notes[normalizedScope(scopeItem)]
 }
 
 func (s *Scope) RootDomains() []string {
 	if len(s.rootDomainMap) == 0 {
-		s.rootDomainMap = s.GetRootDomainMap(true)
-		s.rootDomainSorted = sortedScopeKeys(s.rootDomainMap)
+		s.rootDomainSorted = s.Compile().rootDomains
+		s.rootDomainMap = map[string]bool{}
+		for _, rootDomain := range s.rootDomainSorted {
+			s.rootDomainMap[rootDomain] = true
+		}
 	}
 	return s.rootDomainSorted
 }
 
-func (s *Scope) GetRootDomainMap(checkInScope bool) map[string]bool {
+// getRootDomainMap returns the registrable domains of the domains in scope,
+// leaving out those excludes has excluded when it isn't nil.
+func (s *Scope) getRootDomainMap(excludes *Matcher) map[string]bool {
 	rootDomainMap := make(map[string]bool)
-	for domain := range s.Domains {
+	for domain := range s.domains {
 		rootDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
 		if err != nil {
 			log.Println("root domain err", err)
 			continue
 		}
-		if !checkInScope || s.IsDomainInScope(rootDomain, false) {
+		if excludes == nil || !excludes.ExcludesDomain(rootDomain) {
 			rootDomainMap[rootDomain] = true
 		}
 	}
 	return rootDomainMap
 }
 
-func (s *Scope) GetRootDomainSlice(checkInScope bool) []string {
-	rootDomainMap := s.GetRootDomainMap(checkInScope)
-	return sortedScopeKeys(rootDomainMap)
+// AllRootDomains returns the registrable domains of every domain in scope,
+// including those that have been excluded.
+func (s *Scope) AllRootDomains() []string {
+	return sortedScopeKeys(s.getRootDomainMap(nil))
 }
 
+// AllDomains returns the domains added to the scope.
 func (s *Scope) AllDomains() []string {
-	return sortedScopeKeys(s.Domains)
+	return sortedScopeKeys(s.domains)
+}
+
+// readNotes reads tab separated scope item and note pairs.
+func readNotes(path string) (map[string]string, error) {
+	notes := map[string]string{}
+	lines, err := fileutil.ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		item, note, found := strings.Cut(line, "\t")
+		item = strings.ToLower(strings.TrimSpace(item))
+		if !found || item == "" {
+			continue
+		}
+		notes[item] = strings.TrimSpace(note)
+	}
+	return notes, nil
+}
+
+// readWindows reads testing windows, skipping blank lines. Windows are free
+// form so they are kept as written.
+func readWindows(path string) ([]string, error) {
+	windows := []string{}
+	lines, err := fileutil.ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			windows = append(windows, line)
+		}
+	}
+	return windows, nil
 }
-func (s *Scope) CanAddIP(ipAddr *net.IP) bool {
-	// is IP explicitly blocked
-	return s.IsIPInScope(ipAddr, false)
+
+// removeScopeFile removes an optional scope file, which may not exist.
+func removeScopeFile(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
 }
 
-func (s *Scope) CanAddDomain(domainToCheck string) bool {
-	// is domain explicitly blocked
-	return s.IsDomainInScope(domainToCheck, false)
+func writeNotes(path string, notes map[string]string) error {
+	items := []string{}
+	for item := range notes {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+
+	lines := []string{}
+	for _, item := range items {
+		lines = append(lines, item+"\t"+notes[item])
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
 }
 
 func normalizedScope(scopeItem string) string {
@@ -457,6 +637,11 @@ func normalizedScope(scopeItem string) string {
 		return ""
 	}
 
+	addr, err := netip.ParseAddr(scopeItem)
+	if err == nil {
+		return addr.Unmap().String()
+	}
+
 	containsProto := strings.Contains(scopeItem, "://")
 	if !containsProto && strings.Contains(scopeItem, "/") {
 		// perhaps we have a CIDR
@@ -481,15 +666,6 @@ func normalizedScope(scopeItem string) string {
 		if len(parsedURL.Host) > 0 {
 			hostname := strings.TrimSuffix(parsedURL.Hostname(), ".")
 			return hostname
-			//_, err := publicsuffix.EffectiveTLDPlusOne(hostname)
-			//if err == nil {
-			//	if state.Debug {
-			//		log.Println("hostname", hostname)
-			//	}
-			//	return hostname
-			//} else {
-			//	log.Println("root domain err", err)
-			//}
 		}
 	}
 
@@ -497,60 +673,11 @@ func normalizedScope(scopeItem string) string {
 	return ""
 }
 
-func normalizeAndExpandStringSlice(scopeItemsToCheck []string, all bool) (expandedIPs []string, normalizedIPAddrs []*net.IP, normalizedHostnames []string) {
-
-	for _, scopeToCheck := range scopeItemsToCheck {
-		normalized := normalizedScope(scopeToCheck)
-		if normalized == "" {
-			continue
-		}
-		ipAddrs, err := utils.GetAllIPs(normalized, all)
-		if err == nil {
-			if strings.Contains(normalized, "/") {
-				for _, ip := range ipAddrs {
-					expandedIPs = append(expandedIPs, ip.String())
-				}
-			}
-			normalizedIPAddrs = append(normalizedIPAddrs, ipAddrs...)
-		} else {
-			normalizedHostnames = append(normalizedHostnames, normalized)
-		}
-	}
-	return
-}
-
-func (s *Scope) populateIncludes() {
-	s.inScopeCIDRs = map[string]*net.IPNet{}
-	s.populateInScopeCIDRs(s.IPv4)
-	s.populateInScopeCIDRs(s.IPv6)
-}
-
 func (s *Scope) populateExcludes() {
-
-	s.excludedCIDRs = map[string]*net.IPNet{}
-	s.excludedIPAddrs = map[string]bool{}
 	s.excludedHostnames = map[string]bool{}
-
-	for scopeItem := range s.Excludes {
-		_, ok := s.excludedCIDRs[scopeItem]
-		if !ok {
-			_, ipNet, err := net.ParseCIDR(scopeItem)
-			if err == nil {
-				s.excludedCIDRs[scopeItem] = ipNet
-				continue
-			}
-		}
-
-		_, ok = s.excludedIPAddrs[scopeItem]
-		if !ok {
-			ip := net.ParseIP(scopeItem)
-			if ip != nil {
-				s.excludedIPAddrs[ip.String()] = true
-				continue
-			}
-		}
-
-		s.excludedHostnames[scopeItem] = true
+	_, _, hostnames := getCIDRsIPsHostname(s.excludes)
+	for _, hostname := range hostnames {
+		s.excludedHostnames[hostname] = true
 	}
 }
 
@@ -574,7 +701,7 @@ func getCIDRsIPsHostname(scopeMap map[string]bool) (cidrs []*net.IPNet, ipAddrs
 	return
 }
 
-func sortedScopeKeys(mapWithStringKeys map[string]bool) []string {
+func sortedScopeKeys[V any](mapWithStringKeys map[string]V) []string {
 	keys := []string{}
 	for key := range mapWithStringKeys {
 		keys = append(keys, key)
@@ -582,20 +709,3 @@ func sortedScopeKeys(mapWithStringKeys map[string]bool) []string {
 	sort.Strings(keys)
 	return keys
 }
-
-func (s *Scope) populateInScopeCIDRs(ipScopeMap map[string]bool) {
-
-	for ip := range ipScopeMap {
-		if strings.Contains(ip, "/") {
-			_, ok := s.inScopeCIDRs[ip]
-			if !ok {
-				_, ipNet, err := net.ParseCIDR(ip)
-				if err != nil {
-					panic(err)
-				}
-
-				s.inScopeCIDRs[ip] = ipNet
-			}
-		}
-	}
-}
@@ -53,16 +53,16 @@ func TestScope_Add_IPv4(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Scope{
-				Path:        tt.fields.Path,
-				Description: tt.fields.Description,
-				IPv4:        tt.fields.IPv4,
-				Domains:     tt.fields.Domains,
-				IPv6:        tt.fields.IPv6,
-				Excludes:    tt.fields.Exclude,
+				path:        tt.fields.Path,
+				description: tt.fields.Description,
+				ipv4:        tt.fields.IPv4,
+				domains:     tt.fields.Domains,
+				ipv6:        tt.fields.IPv6,
+				excludes:    tt.fields.Exclude,
 			}
-			s.Add(false, tt.args.scopeItems...)
+			s.Add(tt.args.scopeItems...)
 
-			if got := s.IPv4; !reflect.DeepEqual(got, tt.want) {
+			if got := s.ipv4; !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("IPv4 = %v, want %v", got, tt.want)
 			}
 		})
@@ -116,16 +116,16 @@ func TestScope_Add_Domains(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Scope{
-				Path:        tt.fields.Path,
-				Description: tt.fields.Description,
-				IPv4:        tt.fields.IPv4,
-				Domains:     tt.fields.Domains,
-				IPv6:        tt.fields.IPv6,
-				Excludes:    tt.fields.Exclude,
+				path:        tt.fields.Path,
+				description: tt.fields.Description,
+				ipv4:        tt.fields.IPv4,
+				domains:     tt.fields.Domains,
+				ipv6:        tt.fields.IPv6,
+				excludes:    tt.fields.Exclude,
 			}
-			s.Add(false, tt.args.scopeItems...)
+			s.Add(tt.args.scopeItems...)
 
-			if got := s.Domains; !reflect.DeepEqual(got, tt.want) {
+			if got := s.domains; !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Domains = %v, want %v", got, tt.want)
 			}
 		})
@@ -179,16 +179,16 @@ func TestScope_Add_IPv6(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Scope{
-				Path:        tt.fields.Path,
-				Description: tt.fields.Description,
-				IPv4:        tt.fields.IPv4,
-				Domains:     tt.fields.Domains,
-				IPv6:        tt.fields.IPv6,
-				Excludes:    tt.fields.Exclude,
+				path:        tt.fields.Path,
+				description: tt.fields.Description,
+				ipv4:        tt.fields.IPv4,
+				domains:     tt.fields.Domains,
+				ipv6:        tt.fields.IPv6,
+				excludes:    tt.fields.Exclude,
 			}
-			s.Add(false, tt.args.scopeItems...)
+			s.Add(tt.args.scopeItems...)
 
-			if got := s.IPv6; !reflect.DeepEqual(got, tt.want) {
+			if got := s.ipv6; !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("IPv6 = %v, want %v", got, tt.want)
 			}
 		})
@@ -291,14 +291,14 @@ func TestScope_Prune(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Scope{
-				Path:        tt.fields.Path,
-				Description: tt.fields.Description,
-				IPv4:        tt.fields.IPv4,
-				Domains:     tt.fields.Domains,
-				IPv6:        tt.fields.IPv6,
-				Excludes:    tt.fields.Excludes,
+				path:        tt.fields.Path,
+				description: tt.fields.Description,
+				ipv4:        tt.fields.IPv4,
+				domains:     tt.fields.Domains,
+				ipv6:        tt.fields.IPv6,
+				excludes:    tt.fields.Excludes,
 			}
-			got := s.Prune(false, tt.args.scopeItemsToCheck...)
+			got := s.Prune(tt.args.scopeItemsToCheck, ExpandOptions{})
 
 			if len(got) != len(tt.want) {
 				t.Errorf("Prune returned wrong length: got:%v, wanted:%v", got, tt.want)
@@ -358,12 +358,12 @@ func TestScope_AllIPs(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &Scope{
-				Path:        tt.fields.Path,
-				Description: tt.fields.Description,
-				IPv4:        tt.fields.IPv4,
-				Domains:     tt.fields.Domains,
-				IPv6:        tt.fields.IPv6,
-				Excludes:    tt.fields.Excludes,
+				path:        tt.fields.Path,
+				description: tt.fields.Description,
+				ipv4:        tt.fields.IPv4,
+				domains:     tt.fields.Domains,
+				ipv6:        tt.fields.IPv6,
+				excludes:    tt.fields.Excludes,
 			}
 			if got := s.AllIPs(); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("AllIPs() = %v, want %v", got, tt.want)
@@ -371,3 +371,12 @@ func TestScope_AllIPs(t *testing.T) {
 		})
 	}
 }
+
+func openScoper(t *testing.T, dir string) *Scoper {
+	t.Helper()
+	scoper, err := Open(dir, WithCreate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scoper
+}
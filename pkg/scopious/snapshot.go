@@ -0,0 +1,311 @@
+package scopious
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the canonical form changes, as that
+// changes the digest of the same scope.
+const snapshotVersion = 2
+
+// scopeDirSnapshots is the directory in a scope directory holding the
+// snapshots it has been frozen into.
+const scopeDirSnapshots = "snapshots"
+
+// Snapshot is a scope frozen at the point it was approved. The digest covers
+// the canonical form of the scope and when it was frozen, so neither can be
+// changed without it showing.
+type Snapshot struct {
+	Version     int       `json:"version"`
+	Scope       string    `json:"scope"`
+	Frozen      time.Time `json:"frozen"`
+	Description string    `json:"description,omitempty"`
+	Windows     []string  `json:"windows"`
+	IPv4        []string  `json:"ipv4"`
+	IPv6        []string  `json:"ipv6"`
+	Domains     []string  `json:"domains"`
+	Excludes    []string  `json:"excludes"`
+	Digest      string    `json:"digest"`
+	// PublicKey and Signature are set when the snapshot is signed, the
+	// signature covers the canonical form.
+	PublicKey ed25519.PublicKey `json:"public_key,omitempty"`
+	Signature []byte            `json:"signature,omitempty"`
+}
+
+// SnapshotDeviation is a difference between a snapshot and the live scope.
+type SnapshotDeviation struct {
+	// Change is added when the live scope has the item and the snapshot
+	// doesn't, removed the other way around.
+	Change string `json:"change"`
+	Field  string `json:"field"`
+	Item   string `json:"item"`
+}
+
+// Freeze snapshots the scope.
+func (s *Scope) Freeze() *Snapshot {
+	return s.freezeAt(time.Now())
+}
+
+func (s *Scope) freezeAt(frozen time.Time) *Snapshot {
+	windows := append([]string{}, s.windows...)
+	sort.Strings(windows)
+
+	snapshot := &Snapshot{
+		Version:     snapshotVersion,
+		Scope:       s.Name(),
+		Frozen:      frozen.UTC().Truncate(time.Second),
+		Description: s.description,
+		Windows:     windows,
+		IPv4:        s.IPv4(),
+		IPv6:        s.IPv6(),
+		Domains:     s.AllDomains(),
+		Excludes:    s.Excludes(),
+	}
+	snapshot.Digest = snapshot.canonicalDigest()
+	return snapshot
+}
+
+// Canonical is the deterministic text form of the snapshot that is hashed
+// and signed. Every line is a field name, a space and a value, fields are
+// always in the same order and their values sorted.
+func (snapshot *Snapshot) Canonical() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "scopious snapshot v%d\n", snapshot.Version)
+	fmt.Fprintf(&buf, "scope %s\n", snapshot.Scope)
+	fmt.Fprintf(&buf, "frozen %s\n", snapshot.Frozen.UTC().Format(time.RFC3339))
+	if snapshot.Description != "" {
+		for _, line := range strings.Split(snapshot.Description, "\n") {
+			fmt.Fprintf(&buf, "description %s\n", line)
+		}
+	}
+	for _, field := range snapshot.fields() {
+		for _, item := range field.items {
+			fmt.Fprintf(&buf, "%s %s\n", field.name, item)
+		}
+	}
+	return buf.Bytes()
+}
+
+type snapshotField struct {
+	name  string
+	items []string
+}
+
+func (snapshot *Snapshot) fields() []snapshotField {
+	return []snapshotField{
+		{name: "window", items: snapshot.Windows},
+		{name: "ipv4", items: snapshot.IPv4},
+		{name: "ipv6", items: snapshot.IPv6},
+		{name: "domain", items: snapshot.Domains},
+		{name: "exclude", items: snapshot.Excludes},
+	}
+}
+
+func (snapshot *Snapshot) canonicalDigest() string {
+	sum := sha256.Sum256(snapshot.Canonical())
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Sign signs the canonical form of the snapshot with key.
+func (snapshot *Snapshot) Sign(key ed25519.PrivateKey) {
+	snapshot.PublicKey = key.Public().(ed25519.PublicKey)
+	snapshot.Signature = ed25519.Sign(key, snapshot.Canonical())
+}
+
+// Verify checks the snapshot hasn't been changed since it was frozen. When
+// trusted is given the snapshot must have been signed with its private key,
+// otherwise a signature is only checked against the key it carries.
+func (snapshot *Snapshot) Verify(trusted ed25519.PublicKey) error {
+	if snapshot.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	if snapshot.canonicalDigest() != snapshot.Digest {
+		return errors.New("snapshot digest does not match its contents")
+	}
+
+	if trusted != nil {
+		if len(snapshot.Signature) == 0 {
+			return errors.New("snapshot is not signed")
+		}
+		if !trusted.Equal(snapshot.PublicKey) {
+			return errors.New("snapshot was signed with another key")
+		}
+	}
+	if len(snapshot.Signature) > 0 {
+		if len(snapshot.PublicKey) != ed25519.PublicKeySize || !ed25519.Verify(snapshot.PublicKey, snapshot.Canonical(), snapshot.Signature) {
+			return errors.New("snapshot signature is invalid")
+		}
+	}
+	return nil
+}
+
+// Deviations lists how scope differs from the snapshot.
+func (snapshot *Snapshot) Deviations(s *Scope) []SnapshotDeviation {
+	live := s.Freeze()
+	deviations := []SnapshotDeviation{}
+	if live.Description != snapshot.Description {
+		deviations = append(deviations, SnapshotDeviation{Change: "changed", Field: "description", Item: live.Description})
+	}
+
+	liveFields := live.fields()
+	for i, field := range snapshot.fields() {
+		frozen := map[string]bool{}
+		for _, item := range field.items {
+			frozen[item] = true
+		}
+		current := map[string]bool{}
+		for _, item := range liveFields[i].items {
+			current[item] = true
+			if !frozen[item] {
+				deviations = append(deviations, SnapshotDeviation{Change: "added", Field: field.name, Item: item})
+			}
+		}
+		for _, item := range field.items {
+			if !current[item] {
+				deviations = append(deviations, SnapshotDeviation{Change: "removed", Field: field.name, Item: item})
+			}
+		}
+	}
+	return deviations
+}
+
+// LoadSnapshot reads a snapshot written by Snapshot.Save.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	err = json.Unmarshal(data, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot as JSON.
+func (snapshot *Snapshot) Save(path string) error {
+	return snapshot.save(path, os.O_TRUNC)
+}
+
+func (snapshot *Snapshot) save(path string, flag int) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SaveSnapshot keeps snapshot with the scope, returning where it was written.
+// Snapshots are named for the second they were frozen in and are never
+// overwritten, a second snapshot frozen in the same second is refused.
+func (s *Scope) SaveSnapshot(snapshot *Snapshot) (string, error) {
+	dir := filepath.Join(s.path, scopeDirSnapshots)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, snapshot.Frozen.Format("20060102T150405Z")+".json")
+	err = snapshot.save(path, os.O_EXCL)
+	if errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("snapshot %s already exists, freeze again in a second", path)
+	}
+	return path, err
+}
+
+// LatestSnapshot returns the snapshot the scope was most recently approved
+// in, or nil when it has never been frozen. Snapshots that fail Verify with
+// trusted are skipped, and the latest signed snapshot is preferred over
+// unsigned ones.
+func (s *Scope) LatestSnapshot(trusted ed25519.PublicKey) (*Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(s.path, scopeDirSnapshots, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// names are timestamps, so they sort by when they were frozen
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	var unsigned *Snapshot
+	for _, path := range paths {
+		snapshot, err := LoadSnapshot(path)
+		if err != nil || snapshot.Verify(trusted) != nil {
+			continue
+		}
+		if len(snapshot.Signature) > 0 {
+			return snapshot, nil
+		}
+		if unsigned == nil {
+			unsigned = snapshot
+		}
+	}
+	return unsigned, nil
+}
+
+// LoadSigningKey reads an Ed25519 private key from a PKCS #8 PEM file, like
+// those written by openssl genpkey -algorithm ed25519.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 private key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads an Ed25519 public key from a PKIX PEM file, like those
+// written by openssl pkey -pubout.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 public key", path)
+	}
+	return publicKey, nil
+}
+
+func readPEM(path string, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s: no %s PEM block", path, blockType)
+	}
+	return block.Bytes, nil
+}
@@ -0,0 +1,197 @@
+package scopious
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func snapshotTestScope(t *testing.T) *Scope {
+	s := NewScopeFromPath(filepath.Join(t.TempDir(), "external"))
+	s.Add("example.com", "203.0.113.0/24", "2001:db8::/64")
+	s.AddExclude("admin.example.com")
+	s.SetDescription("External infrastructure\nProduction only")
+	s.SetWindows([]string{"2026-11-02 09:00-17:00 UTC", "2026-11-01 09:00-17:00 UTC"})
+	return s
+}
+
+func TestScope_Freeze(t *testing.T) {
+	s := snapshotTestScope(t)
+	frozen := time.Date(2026, 10, 30, 14, 0, 0, 0, time.UTC)
+	snapshot := s.freezeAt(frozen)
+
+	want := `scopious snapshot v2
+scope external
+frozen 2026-10-30T14:00:00Z
+description External infrastructure
+description Production only
+window 2026-11-01 09:00-17:00 UTC
+window 2026-11-02 09:00-17:00 UTC
+ipv4 203.0.113.0/24
+ipv6 2001:db8::/64
+domain example.com
+exclude admin.example.com
+`
+	if got := string(snapshot.Canonical()); got != want {
+		t.Errorf("Canonical() = %s, want %s", got, want)
+	}
+
+	// the same scope loaded again has the same digest
+	err := s.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewScopeFromPath(s.path)
+	err = loaded.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.freezeAt(frozen).Digest; got != snapshot.Digest {
+		t.Errorf("Digest = %s after loading, want %s", got, snapshot.Digest)
+	}
+	if err := snapshot.Verify(nil); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	// when it was frozen is covered too
+	snapshot.Frozen = snapshot.Frozen.Add(-24 * time.Hour)
+	if err := snapshot.Verify(nil); err == nil {
+		t.Error("expected an error verifying a snapshot with its frozen time changed")
+	}
+}
+
+func TestSnapshot_Verify(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+
+	snapshot := snapshotTestScope(t).Freeze()
+	if err := snapshot.Verify(publicKey); err == nil {
+		t.Error("expected an error verifying an unsigned snapshot against a key")
+	}
+
+	snapshot.Sign(privateKey)
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	err := snapshot.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Verify(publicKey); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	if err := loaded.Verify(otherKey); err == nil {
+		t.Error("expected an error verifying against another key")
+	}
+
+	loaded.Domains = append(loaded.Domains, "example.org")
+	if err := loaded.Verify(nil); err == nil {
+		t.Error("expected an error verifying a changed snapshot")
+	}
+	// updating the digest isn't enough once it is signed
+	loaded.Digest = loaded.canonicalDigest()
+	if err := loaded.Verify(nil); err == nil {
+		t.Error("expected an error verifying a changed signed snapshot")
+	}
+}
+
+func TestSnapshot_Deviations(t *testing.T) {
+	s := snapshotTestScope(t)
+	snapshot := s.Freeze()
+	if deviations := snapshot.Deviations(s); len(deviations) != 0 {
+		t.Errorf("Deviations() = %v, want none", deviations)
+	}
+
+	s.Add("example.org")
+	s.RemoveExclude("admin.example.com")
+	want := []SnapshotDeviation{
+		{Change: "added", Field: "domain", Item: "example.org"},
+		{Change: "removed", Field: "exclude", Item: "admin.example.com"},
+	}
+	if got := snapshot.Deviations(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("Deviations() = %v, want %v", got, want)
+	}
+
+	path, err := s.SaveSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := s.LatestSnapshot(nil)
+	if err != nil || latest == nil || latest.Digest != snapshot.Digest {
+		t.Errorf("LatestSnapshot() = %v, %v, want the snapshot saved to %s", latest, err, path)
+	}
+
+	// a second snapshot in the same second doesn't replace the first
+	again := s.freezeAt(snapshot.Frozen)
+	if _, err := s.SaveSnapshot(again); err == nil {
+		t.Error("SaveSnapshot() overwrote a snapshot frozen in the same second")
+	}
+	if kept, err := LoadSnapshot(path); err != nil || kept.Digest != snapshot.Digest {
+		t.Errorf("LoadSnapshot() = %v, %v, want the first snapshot", kept, err)
+	}
+	if latest, _ := NewScopeFromPath(t.TempDir()).LatestSnapshot(nil); latest != nil {
+		t.Errorf("LatestSnapshot() = %v for a scope never frozen", latest)
+	}
+}
+
+func TestScope_LatestSnapshot(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	s := snapshotTestScope(t)
+	frozen := time.Date(2026, 10, 30, 14, 0, 0, 0, time.UTC)
+
+	signed := s.freezeAt(frozen)
+	signed.Sign(privateKey)
+	s.SaveSnapshot(signed)
+	// newer snapshots that are unsigned or have been altered aren't used
+	s.SaveSnapshot(s.freezeAt(frozen.Add(time.Hour)))
+	altered := s.freezeAt(frozen.Add(2 * time.Hour))
+	altered.Sign(privateKey)
+	altered.Domains = append(altered.Domains, "example.org")
+	s.SaveSnapshot(altered)
+
+	for _, trusted := range []ed25519.PublicKey{nil, publicKey} {
+		latest, err := s.LatestSnapshot(trusted)
+		if err != nil || latest == nil || !latest.Frozen.Equal(frozen) {
+			t.Errorf("LatestSnapshot(%v) = %v, %v, want the signed snapshot", trusted, latest, err)
+		}
+	}
+
+	unsigned := NewScopeFromPath(t.TempDir())
+	unsigned.SaveSnapshot(unsigned.freezeAt(frozen))
+	if latest, _ := unsigned.LatestSnapshot(nil); latest == nil {
+		t.Error("LatestSnapshot() = nil, want the unsigned snapshot when none are signed")
+	}
+	if latest, _ := unsigned.LatestSnapshot(publicKey); latest != nil {
+		t.Errorf("LatestSnapshot() = %v, want none signed with the trusted key", latest)
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	dir := t.TempDir()
+
+	der, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	keyPath := filepath.Join(dir, "key.pem")
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	der, _ = x509.MarshalPKIXPublicKey(publicKey)
+	publicKeyPath := filepath.Join(dir, "key.pub")
+	os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+
+	loadedKey, err := LoadSigningKey(keyPath)
+	if err != nil || !loadedKey.Equal(privateKey) {
+		t.Errorf("LoadSigningKey() = %v", err)
+	}
+	loadedPublicKey, err := LoadPublicKey(publicKeyPath)
+	if err != nil || !loadedPublicKey.Equal(publicKey) {
+		t.Errorf("LoadPublicKey() = %v", err)
+	}
+	if _, err := LoadSigningKey(publicKeyPath); err == nil {
+		t.Error("expected an error loading a public key as a signing key")
+	}
+}
@@ -0,0 +1,115 @@
+package scopious
+
+import (
+	"math/big"
+	"net/netip"
+	"strings"
+
+	"github.com/analog-substance/scopious/pkg/utils"
+	"golang.org/x/net/publicsuffix"
+)
+
+// AddressStats counts the addresses in scope once excludes are applied.
+// Counts are computed from prefix sizes, so they may exceed an int64 for IPv6.
+type AddressStats struct {
+	Total   *big.Int `json:"total"`
+	IPv4    *big.Int `json:"ipv4"`
+	IPv6    *big.Int `json:"ipv6"`
+	Public  *big.Int `json:"public"`
+	Private *big.Int `json:"private"`
+	// Classes counts the addresses of each address class in scope.
+	Classes  map[string]*big.Int `json:"classes"`
+	Included *big.Int            `json:"included"`
+	Excluded *big.Int            `json:"excluded"`
+}
+
+// ScopeStats sizes a scope for quoting and scheduling.
+type ScopeStats struct {
+	Addresses AddressStats `json:"addresses"`
+	// CIDRs and IPs count the entries in the scope files.
+	CIDRs int `json:"cidrs"`
+	IPs   int `json:"ips"`
+	// EffectiveCIDRs is how many CIDRs the in scope addresses aggregate to.
+	EffectiveCIDRs  int            `json:"effective_cidrs"`
+	Domains         int            `json:"domains"`
+	WildcardDomains int            `json:"wildcard_domains"`
+	RootDomains     map[string]int `json:"root_domains"`
+	ExcludedCIDRs   int            `json:"excluded_cidrs"`
+	ExcludedIPs     int            `json:"excluded_ips"`
+	ExcludedDomains int            `json:"excluded_domains"`
+}
+
+// Stats counts what is in scope without expanding any CIDRs.
+func (s *Scope) Stats() ScopeStats {
+	included := s.IncludedPrefixes()
+	effective := s.EffectivePrefixes()
+	ipv4 := []netip.Prefix{}
+	ipv6 := []netip.Prefix{}
+	for _, prefix := range effective {
+		if prefix.Addr().Is4() {
+			ipv4 = append(ipv4, prefix)
+		} else {
+			ipv6 = append(ipv6, prefix)
+		}
+	}
+
+	total := utils.CountAddresses(effective)
+	classes := map[string]*big.Int{}
+	public := new(big.Int).Set(total)
+	for _, class := range AddressClasses {
+		if class == AddressClassPublic {
+			continue
+		}
+		count := utils.CountAddresses(utils.IntersectPrefixes(effective, ClassPrefixes(class)))
+		if count.Sign() > 0 {
+			classes[class] = count
+			public.Sub(public, count)
+		}
+	}
+	if public.Sign() > 0 {
+		classes[AddressClassPublic] = public
+	}
+
+	stats := ScopeStats{
+		Addresses: AddressStats{
+			Total:    total,
+			IPv4:     utils.CountAddresses(ipv4),
+			IPv6:     utils.CountAddresses(ipv6),
+			Public:   public,
+			Private:  new(big.Int),
+			Classes:  classes,
+			Included: utils.CountAddresses(included),
+			Excluded: utils.CountAddresses(utils.IntersectPrefixes(included, s.ExcludedPrefixes())),
+		},
+		EffectiveCIDRs: len(effective),
+		RootDomains:    map[string]int{},
+	}
+
+	if classes[AddressClassPrivate] != nil {
+		stats.Addresses.Private = classes[AddressClassPrivate]
+	}
+
+	for _, scopeMap := range []map[string]bool{s.ipv4, s.ipv6} {
+		cidrs, ipAddrs, _ := getCIDRsIPsHostname(scopeMap)
+		stats.CIDRs += len(cidrs)
+		stats.IPs += len(ipAddrs)
+	}
+
+	_, _, hostnames := getCIDRsIPsHostname(s.domains)
+	stats.Domains = len(hostnames)
+	for _, hostname := range hostnames {
+		if strings.HasPrefix(hostname, "*.") {
+			stats.WildcardDomains++
+		}
+		rootDomain, err := publicsuffix.EffectiveTLDPlusOne(strings.TrimPrefix(hostname, "*."))
+		if err == nil {
+			stats.RootDomains[rootDomain]++
+		}
+	}
+
+	cidrs, ipAddrs, hostnames := getCIDRsIPsHostname(s.excludes)
+	stats.ExcludedCIDRs = len(cidrs)
+	stats.ExcludedIPs = len(ipAddrs)
+	stats.ExcludedDomains = len(hostnames)
+	return stats
+}
@@ -0,0 +1,81 @@
+package scopious
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScope_Stats(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.ipv4["203.0.113.0/24"] = true
+	s.ipv4["203.0.113.9"] = true
+	s.ipv4["10.0.0.0/30"] = true
+	s.ipv6["2001:db8::/64"] = true
+	s.domains["example.com"] = true
+	s.domains["www.example.com"] = true
+	s.domains["*.api.example.com"] = true
+	s.domains["example.co.uk"] = true
+	s.AddExclude("203.0.113.128/25", "203.0.113.1", "198.51.100.0/24", "admin.example.com")
+
+	got := s.Stats()
+	counts := map[string]string{
+		"total":    got.Addresses.Total.String(),
+		"ipv4":     got.Addresses.IPv4.String(),
+		"ipv6":     got.Addresses.IPv6.String(),
+		"public":   got.Addresses.Public.String(),
+		"private":  got.Addresses.Private.String(),
+		"included": got.Addresses.Included.String(),
+		"excluded": got.Addresses.Excluded.String(),
+	}
+	wantCounts := map[string]string{
+		"total":    "18446744073709551747",
+		"ipv4":     "131",
+		"ipv6":     "18446744073709551616",
+		"public":   "0",
+		"private":  "4",
+		"included": "18446744073709551876",
+		"excluded": "129",
+	}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("Stats() addresses = %v, want %v", counts, wantCounts)
+	}
+
+	if got.Addresses.Classes[AddressClassDocumentation].String() != "18446744073709551743" || len(got.Addresses.Classes) != 2 {
+		t.Errorf("Stats() classes = %v", got.Addresses.Classes)
+	}
+
+	if got.CIDRs != 3 || got.IPs != 1 || got.Domains != 4 || got.WildcardDomains != 1 {
+		t.Errorf("Stats() = %+v", got)
+	}
+	if !reflect.DeepEqual(got.RootDomains, map[string]int{"example.com": 3, "example.co.uk": 1}) {
+		t.Errorf("Stats() RootDomains = %v", got.RootDomains)
+	}
+	if got.ExcludedCIDRs != 2 || got.ExcludedIPs != 1 || got.ExcludedDomains != 1 {
+		t.Errorf("Stats() excludes = %+v", got)
+	}
+}
+
+func TestScope_Stats_IPv6Classes(t *testing.T) {
+	s := NewScopeFromPath("")
+	s.Add("fd00::/120", "fe80::/120", "ff02::/120", "::1", "4000::/120", "2001:db8::/120")
+
+	got := s.Stats()
+	classes := map[string]string{}
+	for class, count := range got.Addresses.Classes {
+		classes[class] = count.String()
+	}
+	want := map[string]string{
+		AddressClassPrivate:       "256",
+		AddressClassLinkLocal:     "256",
+		AddressClassMulticast:     "256",
+		AddressClassLoopback:      "1",
+		AddressClassReserved:      "256",
+		AddressClassDocumentation: "256",
+	}
+	if !reflect.DeepEqual(classes, want) {
+		t.Errorf("Stats() classes = %v, want %v", classes, want)
+	}
+	if got.Addresses.Public.Sign() != 0 || got.Addresses.Total.String() != "1281" {
+		t.Errorf("Stats() public = %s, total = %s", got.Addresses.Public, got.Addresses.Total)
+	}
+}
@@ -0,0 +1,274 @@
+package scopious
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const ImportFormatCSV = "csv"
+
+// ColumnMapping names the spreadsheet columns holding each part of a scope
+// row. Only Asset is required. Column names are matched ignoring case, spaces
+// and punctuation, so "In Scope (Y/N)" matches "inscopeyn".
+type ColumnMapping struct {
+	Asset   string `json:"asset"`
+	Type    string `json:"type"`
+	Include string `json:"include"`
+	Scope   string `json:"scope"`
+	Note    string `json:"note"`
+}
+
+// DefaultColumnMapping is used for any column not given with ParseColumnMapping.
+var DefaultColumnMapping = ColumnMapping{
+	Asset:   "Asset",
+	Type:    "Type",
+	Include: "In Scope",
+	Scope:   "Scope",
+	Note:    "Notes",
+}
+
+// TabularRow is a spreadsheet row mapped to scope items.
+type TabularRow struct {
+	Row     int      `json:"row"`
+	Asset   string   `json:"asset"`
+	Scope   string   `json:"scope"`
+	Include bool     `json:"include"`
+	Note    string   `json:"note"`
+	Items   []string `json:"items"`
+}
+
+// TabularRowError is a spreadsheet row that could not be mapped to scope items.
+type TabularRowError struct {
+	Row   int    `json:"row"`
+	Asset string `json:"asset"`
+	Err   string `json:"error"`
+}
+
+func (e TabularRowError) Error() string {
+	return fmt.Sprintf("row %d: %s: %s", e.Row, e.Err, e.Asset)
+}
+
+// TabularResult holds the mapped and invalid rows of a spreadsheet.
+type TabularResult struct {
+	Rows    []TabularRow      `json:"rows"`
+	Invalid []TabularRowError `json:"invalid"`
+	// Warnings lists excluded targets that were skipped, see ImportResult.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+var columnNameRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+var includeValues = map[string]bool{
+	"y": true, "yes": true, "true": true, "1": true, "x": true,
+	"in": true, "inscope": true, "include": true, "included": true,
+	"n": false, "no": false, "false": false, "0": false,
+	"out": false, "outofscope": false, "exclude": false, "excluded": false,
+}
+
+func normalizedColumnName(name string) string {
+	return columnNameRegexp.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// ParseColumnMapping parses a mapping like
+// asset=Host,include=InScope,scope=Environment,note=Notes. Columns that are not
+// mentioned keep their DefaultColumnMapping name.
+func ParseColumnMapping(mapping string) (ColumnMapping, error) {
+	columns := DefaultColumnMapping
+	for _, pair := range strings.Split(mapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		key, column, found := strings.Cut(pair, "=")
+		column = strings.TrimSpace(column)
+		if !found || column == "" {
+			return columns, fmt.Errorf("invalid column mapping: %s", pair)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "asset":
+			columns.Asset = column
+		case "type":
+			columns.Type = column
+		case "include":
+			columns.Include = column
+		case "scope":
+			columns.Scope = column
+		case "note":
+			columns.Note = column
+		default:
+			return columns, fmt.Errorf("unknown column mapping key: %s", key)
+		}
+	}
+	return columns, nil
+}
+
+// ParseTabularScope reads a CSV spreadsheet export with a header row. Each row
+// is mapped to includes or excludes of the scope named in the scope column, or
+// defaultScope when there isn't one. Row numbers count the header as row 1 so
+// they line up with the spreadsheet.
+func ParseTabularScope(r io.Reader, columns ColumnMapping, defaultScope string) (TabularResult, error) {
+	result := TabularResult{
+		Rows:    []TabularRow{},
+		Invalid: []TabularRowError{},
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return result, fmt.Errorf("error reading header: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			// spreadsheet exports often start with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		index[normalizedColumnName(name)] = i
+	}
+	column := func(name string) int {
+		i, ok := index[normalizedColumnName(name)]
+		if !ok || name == "" {
+			return -1
+		}
+		return i
+	}
+
+	assetColumn := column(columns.Asset)
+	if assetColumn < 0 {
+		return result, fmt.Errorf("asset column not found: %s", columns.Asset)
+	}
+	typeColumn := column(columns.Type)
+	includeColumn := column(columns.Include)
+	scopeColumn := column(columns.Scope)
+	noteColumn := column(columns.Note)
+
+	row := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			return result, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		value := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		asset := value(assetColumn)
+		if asset == "" {
+			// blank rows and section spacers
+			continue
+		}
+
+		tabularRow := TabularRow{
+			Row:     row,
+			Asset:   asset,
+			Scope:   tabularScopeName(value(scopeColumn), defaultScope),
+			Include: true,
+			Note:    value(noteColumn),
+		}
+
+		if includeColumn >= 0 {
+			include, ok := includeValues[normalizedColumnName(value(includeColumn))]
+			if !ok {
+				result.Invalid = append(result.Invalid, TabularRowError{Row: row, Asset: asset, Err: fmt.Sprintf("unknown %s value %q", columns.Include, value(includeColumn))})
+				continue
+			}
+			tabularRow.Include = include
+		}
+
+		if unmappableAssetTypes[strings.ToLower(value(typeColumn))] {
+			result.Invalid = append(result.Invalid, TabularRowError{Row: row, Asset: asset, Err: fmt.Sprintf("unmappable asset type %s", value(typeColumn))})
+			continue
+		}
+
+		mapping := mapAssetIdentifier(asset, !tabularRow.Include)
+		for _, target := range mapping.skipped {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("row %d: %s", row, skippedPathWarning(target)))
+		}
+		if len(mapping.items) == 0 {
+			if len(mapping.skipped) > 0 {
+				continue
+			}
+			result.Invalid = append(result.Invalid, TabularRowError{Row: row, Asset: asset, Err: "invalid scope item"})
+			continue
+		}
+		for _, target := range mapping.invalid {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("row %d: %s", row, invalidTargetWarning(target, asset)))
+		}
+		tabularRow.Items = mapping.items
+		result.Rows = append(result.Rows, tabularRow)
+	}
+	return result, nil
+}
+
+// tabularScopeName turns a column value like "Production EU" into a scope name
+// that is safe to use as a directory, production-eu.
+func tabularScopeName(value string, defaultScope string) string {
+	name := strings.Trim(columnNameRegexp.ReplaceAllString(strings.ToLower(value), "-"), "-")
+	if name == "" {
+		return defaultScope
+	}
+	return name
+}
+
+// ByScope groups the mapped rows into an ImportResult per scope name. Notes
+// are kept for each scope item.
+func (r TabularResult) ByScope() map[string]ImportResult {
+	results := map[string]ImportResult{}
+	for _, row := range r.Rows {
+		result, ok := results[row.Scope]
+		if !ok {
+			result = ImportResult{
+				Includes: []string{},
+				Excludes: []string{},
+				Unmapped: []ProgramAsset{},
+				Notes:    map[string]string{},
+			}
+		}
+
+		if row.Include {
+			result.Includes = append(result.Includes, row.Items...)
+		} else {
+			result.Excludes = append(result.Excludes, row.Items...)
+		}
+		if row.Note != "" {
+			for _, item := range row.Items {
+				result.Notes[item] = row.Note
+			}
+		}
+		results[row.Scope] = result
+	}
+	return results
+}
+
+// ScopeNames returns the sorted names of the scopes the rows belong to.
+func (r TabularResult) ScopeNames() []string {
+	names := map[string]bool{}
+	for _, row := range r.Rows {
+		names[row.Scope] = true
+	}
+
+	sorted := []string{}
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,414 @@
+package scopious
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/analog-substance/util/fileutil"
+	"github.com/fsnotify/fsnotify"
+)
+
+// ScopeChange describes a scope that was reloaded after its files changed.
+// When Err is set the files were invalid and the previous scope was kept.
+type ScopeChange struct {
+	Scope           string    `json:"scope"`
+	Time            time.Time `json:"time"`
+	Added           []string  `json:"added,omitempty"`
+	Removed         []string  `json:"removed,omitempty"`
+	ExcludesAdded   []string  `json:"excludes_added,omitempty"`
+	ExcludesRemoved []string  `json:"excludes_removed,omitempty"`
+	Err             error     `json:"-"`
+}
+
+// ScopeWatcher watches the scope directory and reloads scopes when their files
+// are edited. Reloaded scopes are validated first and swapped in whole, so
+// Scope always returns a complete, valid scope.
+type ScopeWatcher struct {
+	ScopeDir string
+	// Debounce is how long to wait for further changes before reloading, as
+	// editors often write a file in several steps.
+	Debounce time.Duration
+
+	mutex       sync.RWMutex
+	scopes      map[string]*Scope
+	matchers    map[string]*Matcher
+	empty       *Matcher
+	subscribers []func(ScopeChange)
+	watcher     *fsnotify.Watcher
+	timers      map[string]*time.Timer
+	closed      bool
+	done        chan struct{}
+}
+
+// Watch starts watching the scope directory for changes.
+func (scoper *Scoper) Watch() (*ScopeWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &ScopeWatcher{
+		ScopeDir: scoper.dir,
+		Debounce: 100 * time.Millisecond,
+		scopes:   map[string]*Scope{},
+		matchers: map[string]*Matcher{},
+		empty:    NewScopeFromPath("").Compile(),
+		watcher:  watcher,
+		timers:   map[string]*time.Timer{},
+		done:     make(chan struct{}),
+	}
+
+	err = watcher.Add(scoper.dir)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	for name := range scoper.scopes {
+		w.addScope(name, true)
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Scope returns the current version of the named scope, or nil when there is
+// no such scope. The returned scope must not be modified.
+func (w *ScopeWatcher) Scope(name string) *Scope {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.scopes[name]
+}
+
+// Matcher returns the current version of the named scope compiled for
+// matching. Nothing is in scope when there is no such scope.
+func (w *ScopeWatcher) Matcher(name string) *Matcher {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	matcher, ok := w.matchers[name]
+	if !ok {
+		return w.empty
+	}
+	return matcher
+}
+
+// WatchedScope follows a single scope of a ScopeWatcher, for long running
+// commands that check everything against one scope.
+type WatchedScope struct {
+	watcher *ScopeWatcher
+	name    string
+}
+
+// Watched follows the named scope, which may not exist yet.
+func (w *ScopeWatcher) Watched(name string) *WatchedScope {
+	return &WatchedScope{watcher: w, name: name}
+}
+
+// Scope returns the current version of the scope, empty when it doesn't
+// exist. The returned scope must not be modified.
+func (s *WatchedScope) Scope() *Scope {
+	scope := s.watcher.Scope(s.name)
+	if scope == nil {
+		return NewScopeFromPath(filepath.Join(s.watcher.ScopeDir, s.name))
+	}
+	return scope
+}
+
+// Matcher returns the current version of the scope compiled for matching.
+func (s *WatchedScope) Matcher() *Matcher {
+	return s.watcher.Matcher(s.name)
+}
+
+// Subscribe calls fn for every reload, including failed ones.
+func (w *ScopeWatcher) Subscribe(fn func(ScopeChange)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Close stops watching. Pending reloads are dropped.
+func (w *ScopeWatcher) Close() error {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	for _, timer := range w.timers {
+		timer.Stop()
+	}
+	w.mutex.Unlock()
+
+	close(w.done)
+	return w.watcher.Close()
+}
+
+// addScope starts watching a scope. New scopes start out empty so that the
+// first reload reports everything in them as added.
+func (w *ScopeWatcher) addScope(name string, load bool) {
+	path := filepath.Join(w.ScopeDir, name)
+	err := w.watcher.Add(path)
+	if err != nil {
+		return
+	}
+
+	scope := NewScopeFromPath(path)
+	if load {
+		loaded, err := LoadScope(path)
+		if err == nil {
+			scope = loaded
+		}
+	}
+	matcher := scope.Compile()
+	w.mutex.Lock()
+	w.scopes[name] = scope
+	w.matchers[name] = matcher
+	w.mutex.Unlock()
+}
+
+func (w *ScopeWatcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.notify(ScopeChange{Time: time.Now(), Err: err})
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		}
+	}
+}
+
+func (w *ScopeWatcher) handleEvent(event fsnotify.Event) {
+	dir, file := filepath.Split(event.Name)
+	dir = filepath.Clean(dir)
+
+	if dir == filepath.Clean(w.ScopeDir) {
+		if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+			// a scope was removed, or renamed away
+			if w.Scope(file) != nil {
+				w.schedule(file)
+			}
+			return
+		}
+		// a scope was created in the scope directory
+		info, err := os.Stat(event.Name)
+		if err == nil && info.IsDir() && w.Scope(file) == nil {
+			w.addScope(file, false)
+			w.schedule(file)
+		}
+		return
+	}
+
+	if filepath.Dir(dir) != filepath.Clean(w.ScopeDir) || !isScopeFile(file) {
+		// editor swap files and backups
+		return
+	}
+	w.schedule(filepath.Base(dir))
+}
+
+// schedule reloads a scope once its files have stopped changing.
+func (w *ScopeWatcher) schedule(name string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return
+	}
+
+	timer, ok := w.timers[name]
+	if ok {
+		timer.Reset(w.Debounce)
+		return
+	}
+	w.timers[name] = time.AfterFunc(w.Debounce, func() {
+		w.reload(name)
+	})
+}
+
+func (w *ScopeWatcher) reload(name string) {
+	path := filepath.Join(w.ScopeDir, name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		w.removeScope(name)
+		return
+	}
+
+	change := ScopeChange{Scope: name, Time: time.Now()}
+	scope, err := LoadScope(path)
+	if err != nil {
+		change.Err = err
+		w.notify(change)
+		return
+	}
+
+	matcher := scope.Compile()
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return
+	}
+	previous := w.scopes[name]
+	w.scopes[name] = scope
+	w.matchers[name] = matcher
+	w.mutex.Unlock()
+
+	if previous == nil {
+		previous = NewScopeFromPath(scope.path)
+	}
+	w.notifyDiff(change, previous, scope)
+}
+
+// removeScope drops a scope whose directory is gone, leaving nothing in scope
+// for it.
+func (w *ScopeWatcher) removeScope(name string) {
+	w.mutex.Lock()
+	previous, ok := w.scopes[name]
+	if w.closed || !ok {
+		w.mutex.Unlock()
+		return
+	}
+	delete(w.scopes, name)
+	delete(w.matchers, name)
+	delete(w.timers, name)
+	w.mutex.Unlock()
+
+	// the directory is no longer watched once removed
+	w.watcher.Remove(previous.path)
+	w.notifyDiff(ScopeChange{Scope: name, Time: time.Now()}, previous, NewScopeFromPath(previous.path))
+}
+
+// notifyDiff fills in what changed between two versions of a scope and
+// notifies subscribers, when anything did.
+func (w *ScopeWatcher) notifyDiff(change ScopeChange, previous *Scope, scope *Scope) {
+	change.Added, change.Removed = diffItems(previous.allItems(), scope.allItems())
+	change.ExcludesAdded, change.ExcludesRemoved = diffItems(previous.excludes, scope.excludes)
+	if len(change.Added)+len(change.Removed)+len(change.ExcludesAdded)+len(change.ExcludesRemoved) == 0 {
+		return
+	}
+	w.notify(change)
+}
+
+func (w *ScopeWatcher) notify(change ScopeChange) {
+	w.mutex.RLock()
+	subscribers := append([]func(ScopeChange){}, w.subscribers...)
+	w.mutex.RUnlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(change)
+	}
+}
+
+func (s *Scope) allItems() map[string]bool {
+	items := map[string]bool{}
+	for _, scopeMap := range []map[string]bool{s.ipv4, s.ipv6, s.domains} {
+		for item := range scopeMap {
+			items[item] = true
+		}
+	}
+	return items
+}
+
+func diffItems(before map[string]bool, after map[string]bool) (added []string, removed []string) {
+	for item := range after {
+		if !before[item] {
+			added = append(added, item)
+		}
+	}
+	for item := range before {
+		if !after[item] {
+			removed = append(removed, item)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return
+}
+
+// isScopeFile reports whether name is one of the files a scope is loaded
+// from. Snapshots are never loaded, so changes to them are ignored.
+func isScopeFile(name string) bool {
+	switch name {
+	case scopeFileIPv4, scopeFileIPv6, scopeFileDomains, scopeFileExclude, scopeFileNotes,
+		scopeFileDescription, scopeFileWindows, scopeFilePending:
+		return true
+	}
+	return false
+}
+
+// LoadScope loads the scope stored at path, checking every line of its files
+// first. Unlike Scope.Load it returns an error when the files hold something
+// that isn't a valid scope item.
+func LoadScope(path string) (*Scope, error) {
+	errs := []error{}
+	for _, name := range []string{scopeFileIPv4, scopeFileIPv6, scopeFileDomains, scopeFileExclude} {
+		lines, err := fileutil.ReadLowerLines(filepath.Join(path, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			err := validateScopeLine(name, line)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s:%d: %w", filepath.Join(path, name), i+1, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return loadScope(path)
+}
+
+// loadScope loads the scope at path, returning an empty scope along with the
+// error when it can't be read.
+func loadScope(path string) (*Scope, error) {
+	scope := NewScopeFromPath(path)
+	err := scope.Load()
+	if err != nil {
+		scope = NewScopeFromPath(path)
+	}
+	// populate lazily computed state now so the scope is safe to share
+	scope.populateExcludes()
+	scope.RootDomains()
+	return scope, err
+}
+
+func validateScopeLine(file string, line string) error {
+	switch file {
+	case scopeFileIPv4, scopeFileIPv6:
+		if strings.Contains(line, "/") {
+			_, _, err := net.ParseCIDR(line)
+			if err != nil {
+				return fmt.Errorf("invalid CIDR %q", line)
+			}
+			return nil
+		}
+		if net.ParseIP(line) == nil {
+			return fmt.Errorf("invalid IP address %q", line)
+		}
+	default:
+		if normalizedScope(line) == "" {
+			return fmt.Errorf("invalid scope item %q", line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package scopious
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScoper_Watch(t *testing.T) {
+	dir := t.TempDir()
+	scoper := openScoper(t, dir)
+	scoper.GetScope(DefaultScope).Add("example.com")
+	scoper.Save()
+
+	watcher, err := scoper.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	watcher.Debounce = 10 * time.Millisecond
+
+	changes := make(chan ScopeChange, 10)
+	watcher.Subscribe(func(change ScopeChange) {
+		changes <- change
+	})
+	next := func() ScopeChange {
+		t.Helper()
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for scope change")
+		}
+		return ScopeChange{}
+	}
+
+	// editors like vim write a new file and rename it into place
+	domainsPath := filepath.Join(dir, DefaultScope, scopeFileDomains)
+	os.WriteFile(domainsPath+".swp", []byte("example.net\n"), 0644)
+	os.Rename(domainsPath+".swp", domainsPath)
+
+	change := next()
+	if change.Err != nil || !reflect.DeepEqual(change.Added, []string{"example.net"}) || !reflect.DeepEqual(change.Removed, []string{"example.com"}) {
+		t.Errorf("change = %+v", change)
+	}
+	if !watcher.Scope(DefaultScope).IsInScope("www.example.net") {
+		t.Errorf("reloaded scope does not include example.net")
+	}
+
+	os.WriteFile(filepath.Join(dir, DefaultScope, scopeFileIPv4), []byte("10.0.0.0/33\n"), 0644)
+	change = next()
+	if change.Err == nil {
+		t.Errorf("invalid ipv4.txt change.Err = nil")
+	}
+	if !watcher.Scope(DefaultScope).IsInScope("www.example.net") {
+		t.Errorf("invalid change replaced the scope")
+	}
+
+	os.Mkdir(filepath.Join(dir, "internal"), 0755)
+	os.WriteFile(filepath.Join(dir, "internal", scopeFileIPv4), []byte("10.0.0.0/8\n"), 0644)
+	change = next()
+	if change.Scope != "internal" || !reflect.DeepEqual(change.Added, []string{"10.0.0.0/8"}) {
+		t.Errorf("new scope change = %+v", change)
+	}
+
+	// files other than the item lists are reloaded too
+	os.WriteFile(filepath.Join(dir, "internal", scopeFileDescription), []byte("Internal network\n"), 0644)
+	deadline := time.Now().Add(5 * time.Second)
+	for watcher.Scope("internal").Description() != "Internal network" {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the description to reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	os.RemoveAll(filepath.Join(dir, "internal"))
+	change = next()
+	if change.Scope != "internal" || !reflect.DeepEqual(change.Removed, []string{"10.0.0.0/8"}) {
+		t.Errorf("removed scope change = %+v", change)
+	}
+	if watcher.Scope("internal") != nil || watcher.Matcher("internal").Contains("10.0.0.1") {
+		t.Errorf("removed scope is still in use")
+	}
+}
+
+func TestScopeWatcher_Close(t *testing.T) {
+	dir := t.TempDir()
+	scoper := openScoper(t, dir)
+	scoper.GetScope(DefaultScope).Add("example.com")
+	scoper.Save()
+
+	watcher, err := scoper.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.Debounce = 50 * time.Millisecond
+	changes := make(chan ScopeChange, 10)
+	watcher.Subscribe(func(change ScopeChange) {
+		changes <- change
+	})
+
+	// schedule a reload, then close before it is due
+	watcher.schedule(DefaultScope)
+	os.WriteFile(filepath.Join(dir, DefaultScope, scopeFileDomains), []byte("example.net\n"), 0644)
+	watcher.Close()
+
+	select {
+	case change := <-changes:
+		t.Errorf("reloaded after Close: %+v", change)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if watcher.Scope(DefaultScope).IsInScope("example.net") {
+		t.Error("scope changed after Close")
+	}
+	if watcher.Close() != nil {
+		t.Error("closing twice failed")
+	}
+}
+
+func TestLoadScope(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, scopeFileIPv4), []byte("203.0.113.0/24\n\n10.0.0.300\n"), 0644)
+	os.WriteFile(filepath.Join(dir, scopeFileDomains), []byte("example.com\n"), 0644)
+
+	_, err := LoadScope(dir)
+	if err == nil {
+		t.Fatal("LoadScope() error = nil, want invalid IP address error")
+	}
+
+	os.WriteFile(filepath.Join(dir, scopeFileIPv4), []byte("203.0.113.0/24\n"), 0644)
+	scope, err := LoadScope(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scope.IsInScope("203.0.113.5") || !scope.IsInScope("www.example.com") {
+		t.Errorf("LoadScope() scope missing items")
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"hash/maphash"
+)
+
+// bloomHashes is optimal at around 10 bits per item, where about 1% of new
+// items are reported as already seen.
+const bloomHashes = 7
+
+// BloomFilter remembers strings in a fixed amount of memory. It never forgets
+// a string it has seen but may claim to have seen one it hasn't.
+type BloomFilter struct {
+	bits  []uint64
+	seed1 maphash.Seed
+	seed2 maphash.Seed
+}
+
+// NewBloomFilter creates a filter using size bytes of memory.
+func NewBloomFilter(size int) *BloomFilter {
+	return &BloomFilter{
+		bits:  make([]uint64, max(size/8, 1)),
+		seed1: maphash.MakeSeed(),
+		seed2: maphash.MakeSeed(),
+	}
+}
+
+// Add adds item to the filter, reporting whether it was probably there
+// already.
+func (b *BloomFilter) Add(item string) bool {
+	size := uint64(len(b.bits)) * 64
+	h1 := maphash.String(b.seed1, item)
+	h2 := maphash.String(b.seed2, item) | 1
+
+	seen := true
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			seen = false
+			b.bits[word] |= mask
+		}
+	}
+	return seen
+}
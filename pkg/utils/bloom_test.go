@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	filter := NewBloomFilter(16 << 10)
+	for i := 0; i < 10000; i++ {
+		filter.Add(fmt.Sprintf("https://%d.example.com/", i))
+	}
+
+	for i := 0; i < 10000; i++ {
+		if !filter.Add(fmt.Sprintf("https://%d.example.com/", i)) {
+			t.Fatalf("item %d was forgotten", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.Add(fmt.Sprintf("https://%d.example.net/", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 500 {
+		t.Errorf("%d false positives for 10000 new items", falsePositives)
+	}
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// ParseRange parses an address range such as 10.0.0.1-10.0.0.20 or the
+// shorthand 10.0.0.1-20 where only the last octet is given for the end.
+func ParseRange(ipRange string) (netip.Addr, netip.Addr, error) {
+	startStr, endStr, found := strings.Cut(strings.TrimSpace(ipRange), "-")
+	if !found {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("not an address range: %s", ipRange)
+	}
+	startStr = strings.TrimSpace(startStr)
+	endStr = strings.TrimSpace(endStr)
+
+	start, err := netip.ParseAddr(startStr)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, err
+	}
+
+	if start.Is4() && !strings.ContainsAny(endStr, ".:") {
+		// shorthand, only the last octet was supplied
+		endStr = startStr[:strings.LastIndex(startStr, ".")+1] + endStr
+	}
+
+	end, err := netip.ParseAddr(endStr)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, err
+	}
+
+	if start.BitLen() != end.BitLen() || end.Less(start) {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid address range: %s", ipRange)
+	}
+	return start.Unmap(), end.Unmap(), nil
+}
+
+// RangeToPrefixes returns the smallest list of prefixes covering start through end inclusive.
+func RangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	start = start.Unmap()
+	end = end.Unmap()
+	prefixes := []netip.Prefix{}
+	if !start.IsValid() || start.BitLen() != end.BitLen() || end.Less(start) {
+		return prefixes
+	}
+
+	for {
+		bits := start.BitLen()
+		for bits > 0 {
+			candidate := netip.PrefixFrom(start, bits-1).Masked()
+			if candidate.Addr() != start || end.Less(LastAddr(candidate)) {
+				break
+			}
+			bits--
+		}
+
+		prefix := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, prefix)
+
+		last := LastAddr(prefix)
+		if last == end {
+			return prefixes
+		}
+		start = last.Next()
+	}
+}
+
+// LastAddr returns the highest address contained in prefix.
+func LastAddr(prefix netip.Prefix) netip.Addr {
+	prefix = prefix.Masked()
+	addr := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(addr)*8; bit++ {
+		addr[bit/8] |= 1 << (7 - bit%8)
+	}
+	last, _ := netip.AddrFromSlice(addr)
+	return last
+}
+
+type addrRange struct {
+	start netip.Addr
+	end   netip.Addr
+}
+
+// mergedRanges converts prefixes into sorted, non-overlapping address ranges.
+// Adjacent ranges of the same address family are joined.
+func mergedRanges(prefixes []netip.Prefix) []addrRange {
+	ranges := []addrRange{}
+	for _, prefix := range prefixes {
+		if !prefix.IsValid() {
+			continue
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), max(prefix.Bits()-96, 0))
+		}
+		prefix = prefix.Masked()
+		ranges = append(ranges, addrRange{prefix.Addr(), LastAddr(prefix)})
+	}
+
+	slices.SortFunc(ranges, func(a, b addrRange) int {
+		return a.start.Compare(b.start)
+	})
+
+	merged := []addrRange{}
+	for _, r := range ranges {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			next := last.end.Next()
+			if last.start.BitLen() == r.start.BitLen() && (!next.IsValid() || !next.Less(r.start)) {
+				if last.end.Less(r.end) {
+					last.end = r.end
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+func rangesToPrefixes(ranges []addrRange) []netip.Prefix {
+	prefixes := []netip.Prefix{}
+	for _, r := range ranges {
+		prefixes = append(prefixes, RangeToPrefixes(r.start, r.end)...)
+	}
+	return prefixes
+}
+
+// AggregatePrefixes returns the smallest sorted list of prefixes covering
+// exactly the same addresses as prefixes.
+func AggregatePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	return rangesToPrefixes(mergedRanges(prefixes))
+}
+
+// SubtractPrefixes returns the addresses in from that are not in remove.
+func SubtractPrefixes(from []netip.Prefix, remove []netip.Prefix) []netip.Prefix {
+	removeRanges := mergedRanges(remove)
+	result := []addrRange{}
+
+	for _, r := range mergedRanges(from) {
+		remaining := []addrRange{r}
+		for _, cut := range removeRanges {
+			if cut.start.BitLen() != r.start.BitLen() {
+				continue
+			}
+
+			next := []addrRange{}
+			for _, piece := range remaining {
+				if cut.end.Less(piece.start) || piece.end.Less(cut.start) {
+					next = append(next, piece)
+					continue
+				}
+				if piece.start.Less(cut.start) {
+					next = append(next, addrRange{piece.start, cut.start.Prev()})
+				}
+				if cut.end.Less(piece.end) {
+					next = append(next, addrRange{cut.end.Next(), piece.end})
+				}
+			}
+			remaining = next
+		}
+		result = append(result, remaining...)
+	}
+	return rangesToPrefixes(result)
+}
+
+// IntersectPrefixes returns the addresses contained in both a and b.
+func IntersectPrefixes(a []netip.Prefix, b []netip.Prefix) []netip.Prefix {
+	result := []addrRange{}
+	bRanges := mergedRanges(b)
+	for _, ar := range mergedRanges(a) {
+		for _, br := range bRanges {
+			if ar.start.BitLen() != br.start.BitLen() || ar.end.Less(br.start) || br.end.Less(ar.start) {
+				continue
+			}
+			start := ar.start
+			if start.Less(br.start) {
+				start = br.start
+			}
+			end := ar.end
+			if br.end.Less(end) {
+				end = br.end
+			}
+			result = append(result, addrRange{start, end})
+		}
+	}
+	return rangesToPrefixes(result)
+}
+
+// CountAddresses returns how many addresses prefixes cover, counting
+// overlapping prefixes once. Nothing is expanded so IPv6 prefixes are cheap.
+func CountAddresses(prefixes []netip.Prefix) *big.Int {
+	total := new(big.Int)
+	for _, prefix := range AggregatePrefixes(prefixes) {
+		size := new(big.Int).Lsh(big.NewInt(1), uint(prefix.Addr().BitLen()-prefix.Bits()))
+		total.Add(total, size)
+	}
+	return total
+}
+
+// PrefixString formats single address prefixes as a plain address.
+func PrefixString(prefix netip.Prefix) string {
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func prefixes(cidrs ...string) []netip.Prefix {
+	result := []netip.Prefix{}
+	for _, cidr := range cidrs {
+		result = append(result, netip.MustParsePrefix(cidr))
+	}
+	return result
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		ipRange string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{name: "full range", ipRange: "10.0.0.0-10.0.0.255", want: prefixes("10.0.0.0/24")},
+		{name: "shorthand range", ipRange: "10.0.0.1-6", want: prefixes("10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32")},
+		{name: "spaces", ipRange: "192.0.2.10 - 192.0.2.13", want: prefixes("192.0.2.10/31", "192.0.2.12/31")},
+		{name: "ipv6", ipRange: "2001:db8::-2001:db8::ffff", want: prefixes("2001:db8::/112")},
+		{name: "backwards", ipRange: "10.0.0.9-10.0.0.1", wantErr: true},
+		{name: "mixed families", ipRange: "10.0.0.1-2001:db8::1", wantErr: true},
+		{name: "not a range", ipRange: "10.0.0.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := ParseRange(tt.ipRange)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := RangeToPrefixes(start, end); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RangeToPrefixes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRangeToPrefixes_FullSpace(t *testing.T) {
+	got := RangeToPrefixes(netip.MustParseAddr("0.0.0.0"), netip.MustParseAddr("255.255.255.255"))
+	if !reflect.DeepEqual(got, prefixes("0.0.0.0/0")) {
+		t.Errorf("RangeToPrefixes() = %v, want 0.0.0.0/0", got)
+	}
+}
+
+func TestPrefixSetOperations(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         []netip.Prefix
+		b         []netip.Prefix
+		aggregate []netip.Prefix
+		subtract  []netip.Prefix
+		intersect []netip.Prefix
+	}{
+		{
+			name:      "adjacent prefixes merge",
+			a:         prefixes("10.0.0.0/25", "10.0.0.128/25", "10.0.1.0/24"),
+			b:         prefixes("10.0.0.64/26"),
+			aggregate: prefixes("10.0.0.0/23"),
+			subtract:  prefixes("10.0.0.0/26", "10.0.0.128/25", "10.0.1.0/24"),
+			intersect: prefixes("10.0.0.64/26"),
+		},
+		{
+			name:      "families are kept apart",
+			a:         prefixes("10.0.0.0/24", "2001:db8::/64"),
+			b:         prefixes("0.0.0.0/0"),
+			aggregate: prefixes("10.0.0.0/24", "2001:db8::/64"),
+			subtract:  prefixes("2001:db8::/64"),
+			intersect: prefixes("10.0.0.0/24"),
+		},
+		{
+			name:      "no overlap",
+			a:         prefixes("192.0.2.0/24"),
+			b:         prefixes("198.51.100.0/24"),
+			aggregate: prefixes("192.0.2.0/24"),
+			subtract:  prefixes("192.0.2.0/24"),
+			intersect: prefixes(),
+		},
+		{
+			name:      "single address hole",
+			a:         prefixes("192.0.2.0/30"),
+			b:         prefixes("192.0.2.1/32", "192.0.2.1/32"),
+			aggregate: prefixes("192.0.2.0/30"),
+			subtract:  prefixes("192.0.2.0/32", "192.0.2.2/31"),
+			intersect: prefixes("192.0.2.1/32"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AggregatePrefixes(tt.a); !reflect.DeepEqual(got, tt.aggregate) {
+				t.Errorf("AggregatePrefixes() = %v, want %v", got, tt.aggregate)
+			}
+			if got := SubtractPrefixes(tt.a, tt.b); !reflect.DeepEqual(got, tt.subtract) {
+				t.Errorf("SubtractPrefixes() = %v, want %v", got, tt.subtract)
+			}
+			if got := IntersectPrefixes(tt.a, tt.b); !reflect.DeepEqual(got, tt.intersect) {
+				t.Errorf("IntersectPrefixes() = %v, want %v", got, tt.intersect)
+			}
+		})
+	}
+}
+
+func TestCountAddresses(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []netip.Prefix
+		want     string
+	}{
+		{name: "empty", prefixes: prefixes(), want: "0"},
+		{name: "overlap counted once", prefixes: prefixes("10.0.0.0/24", "10.0.0.128/25", "10.0.1.1/32"), want: "257"},
+		{name: "whole IPv4 space", prefixes: prefixes("0.0.0.0/0"), want: "4294967296"},
+		{name: "IPv6", prefixes: prefixes("2001:db8::/32", "10.0.0.0/31"), want: "79228162514264337593543950338"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountAddresses(tt.prefixes); got.String() != tt.want {
+				t.Errorf("CountAddresses() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}